@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+// runBatch runs every task of the file in turn. A failed task is reported
+// and the rest still run, then a summary with one row per task is printed.
+// The plots of task i are saved with the prefix task<i>_.
+func runBatch(cCtx *cli.Context, tasks []data, run func(cCtx *cli.Context, d data, prefix string) (string, error), describe func(d data) (string, string)) error {
+	t := Table{Header: []string{"#", "Name", "Method", "Equation or system", "Result", "Status"}}
+
+	failed := 0
+	for i, task := range tasks {
+		fmt.Printf("=== Task %d", i+1)
+		if len(task.Name) > 0 {
+			fmt.Printf(": %s", task.Name)
+		}
+		fmt.Println(" ===")
+
+		method, problem := describe(task)
+		result, err := run(cCtx, task, fmt.Sprintf("task%d_", i+1))
+		status := "ok"
+		if err != nil {
+			fmt.Println("Error:", err)
+			status = "failed: " + err.Error()
+			failed++
+		}
+		fmt.Println()
+
+		t.Rows = append(t.Rows, []string{fmt.Sprint(i + 1), task.Name, method, problem, result, status})
+	}
+
+	fmt.Println("Summary")
+	if err := t.Write(os.Stdout, cCtx.String("format")); err != nil {
+		return err
+	}
+
+	if len(cCtx.String("report")) > 0 {
+		file, err := os.Create(cCtx.String("report"))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if err := t.Write(file, cCtx.String("format")); err != nil {
+			return err
+		}
+		fmt.Println("Summary saved to", cCtx.String("report"))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tasks failed", failed, len(tasks))
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Elementary functions on big.Float. Each works with guardBits more than
+// asked for and rounds the result back, so all but the last bits are right.
+
+const guardBits = 32
+
+func bigFloat(v float64, prec uint) *big.Float {
+	return new(big.Float).SetPrec(prec).SetFloat64(v)
+}
+
+// bigSmall reports whether |x| < 2^-bits, or x is zero
+func bigSmall(x *big.Float, bits int) bool {
+	return x.Sign() == 0 || x.MantExp(nil) < -bits
+}
+
+// bigExp uses exp(x) = exp(x/2^k)^(2^k) with x/2^k small enough for the
+// Taylor series to converge quickly
+func bigExp(x *big.Float, prec uint) *big.Float {
+	if x.Sign() == 0 {
+		return bigFloat(1, prec)
+	}
+
+	k := 0
+	if e := x.MantExp(nil); e > -8 {
+		k = e + 8
+	}
+	work := prec + guardBits + uint(k)
+
+	r := new(big.Float).SetPrec(work).SetMantExp(x, -k)
+	sum, term := bigFloat(1, work), bigFloat(1, work)
+	for n := 1; ; n++ {
+		term.Mul(term, r)
+		term.Quo(term, bigFloat(float64(n), work))
+		if bigSmall(term, int(work)) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+
+	for i := 0; i < k; i++ {
+		sum.Mul(sum, sum)
+	}
+	return new(big.Float).SetPrec(prec).Set(sum)
+}
+
+// bigLog solves exp(y) = x with Halley's iterations, each one triples the
+// number of correct digits of the float64 start
+func bigLog(x *big.Float, prec uint) (*big.Float, error) {
+	if x.Sign() <= 0 {
+		return nil, fmt.Errorf("ln of %s", x.Text('g', 10))
+	}
+	work := prec + guardBits
+
+	mant := new(big.Float)
+	exp := x.MantExp(mant)
+	m, _ := mant.Float64()
+	y := bigFloat(math.Log(m)+float64(exp)*math.Ln2, work)
+
+	xw := new(big.Float).SetPrec(work).Set(x)
+	for i := 0; i < 64; i++ {
+		// y += 2 (x - e^y) / (x + e^y)
+		ey := bigExp(y, work)
+		num := new(big.Float).SetPrec(work).Sub(xw, ey)
+		den := new(big.Float).SetPrec(work).Add(xw, ey)
+		step := num.Quo(num, den)
+		step.Mul(step, bigFloat(2, work))
+		y.Add(y, step)
+		if bigSmall(step, int(work)-8) {
+			break
+		}
+	}
+	return new(big.Float).SetPrec(prec).Set(y), nil
+}
+
+// bigPi uses Machin's formula pi = 16 atan(1/5) - 4 atan(1/239)
+func bigPi(prec uint) *big.Float {
+	work := prec + guardBits
+	a := bigAtanInverse(5, work)
+	b := bigAtanInverse(239, work)
+	a.Mul(a, bigFloat(16, work))
+	b.Mul(b, bigFloat(4, work))
+	return new(big.Float).SetPrec(prec).Sub(a, b)
+}
+
+// bigAtanInverse is atan(1/n) = 1/n - 1/(3n^3) + 1/(5n^5) - ...
+func bigAtanInverse(n float64, prec uint) *big.Float {
+	power := new(big.Float).SetPrec(prec).Quo(bigFloat(1, prec), bigFloat(n, prec))
+	n2 := bigFloat(n*n, prec)
+	sum := new(big.Float).SetPrec(prec).Set(power)
+	for k := 1; ; k++ {
+		power.Quo(power, n2)
+		term := new(big.Float).SetPrec(prec).Quo(power, bigFloat(float64(2*k+1), prec))
+		if bigSmall(term, int(prec)) {
+			break
+		}
+		if k%2 == 1 {
+			sum.Sub(sum, term)
+		} else {
+			sum.Add(sum, term)
+		}
+	}
+	return sum
+}
+
+// bigSinCos reduces x by whole turns and sums the Taylor series of sin or
+// cos. The reduction needs as many extra bits as x has before the point.
+func bigSinCos(x *big.Float, prec uint, sine bool) *big.Float {
+	work := prec + guardBits
+	if e := x.MantExp(nil); e > 0 {
+		work += uint(e)
+	}
+
+	twoPi := bigPi(work)
+	twoPi.Mul(twoPi, bigFloat(2, work))
+	turns, _ := new(big.Float).SetPrec(work).Quo(x, twoPi).Int(nil)
+	r := new(big.Float).SetPrec(work).SetInt(turns)
+	r.Mul(r, twoPi)
+	r.Sub(new(big.Float).SetPrec(work).Set(x), r)
+
+	r2 := new(big.Float).SetPrec(work).Mul(r, r)
+	r2.Neg(r2)
+
+	term := bigFloat(1, work)
+	n := 0
+	if sine {
+		term.Set(r)
+		n = 1
+	}
+	sum := new(big.Float).SetPrec(work).Set(term)
+	for ; ; n += 2 {
+		term.Mul(term, r2)
+		term.Quo(term, bigFloat(float64((n+1)*(n+2)), work))
+		if bigSmall(term, int(work)) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	return new(big.Float).SetPrec(prec).Set(sum)
+}
+
+// bigAtan halves the angle with atan(x) = 2 atan(x / (1 + sqrt(1 + x^2)))
+// until the series converges fast
+func bigAtan(x *big.Float, prec uint) *big.Float {
+	work := prec + guardBits
+	y := new(big.Float).SetPrec(work).Set(x)
+
+	halvings := 0
+	for !bigSmall(y, 3) {
+		t := new(big.Float).SetPrec(work).Mul(y, y)
+		t.Add(t, bigFloat(1, work))
+		t.Sqrt(t)
+		t.Add(t, bigFloat(1, work))
+		y.Quo(y, t)
+		halvings++
+	}
+
+	y2 := new(big.Float).SetPrec(work).Mul(y, y)
+	y2.Neg(y2)
+	power := new(big.Float).SetPrec(work).Set(y)
+	sum := new(big.Float).SetPrec(work).Set(y)
+	for k := 1; ; k++ {
+		power.Mul(power, y2)
+		term := new(big.Float).SetPrec(work).Quo(power, bigFloat(float64(2*k+1), work))
+		if bigSmall(term, int(work)) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+
+	return new(big.Float).SetPrec(prec).SetMantExp(sum, halvings)
+}
+
+func bigAsin(x *big.Float, prec uint) (*big.Float, error) {
+	work := prec + guardBits
+	one := bigFloat(1, work)
+	switch new(big.Float).Abs(x).Cmp(one) {
+	case 1:
+		return nil, fmt.Errorf("asin of %s", x.Text('g', 10))
+	case 0:
+		halfPi := bigPi(prec)
+		halfPi.SetMantExp(halfPi, -1)
+		if x.Sign() < 0 {
+			halfPi.Neg(halfPi)
+		}
+		return halfPi, nil
+	}
+
+	// asin(x) = atan(x / sqrt(1 - x^2))
+	t := new(big.Float).SetPrec(work).Mul(x, x)
+	t.Sub(one, t)
+	t.Sqrt(t)
+	t.Quo(x, t)
+	return bigAtan(t, prec), nil
+}
+
+func bigPow(x, y *big.Float, prec uint) (*big.Float, error) {
+	work := prec + guardBits
+
+	if y.IsInt() && y.MantExp(nil) < 31 {
+		n, _ := y.Int64()
+		neg := n < 0
+		if neg {
+			n = -n
+		}
+
+		ans, base := bigFloat(1, work), new(big.Float).SetPrec(work).Set(x)
+		for ; n > 0; n /= 2 {
+			if n%2 == 1 {
+				ans.Mul(ans, base)
+			}
+			base.Mul(base, base)
+		}
+		if neg {
+			if ans.Sign() == 0 {
+				return nil, errors.New("division by zero")
+			}
+			ans.Quo(bigFloat(1, work), ans)
+		}
+		return new(big.Float).SetPrec(prec).Set(ans), nil
+	}
+
+	if x.Sign() == 0 && y.Sign() > 0 {
+		return new(big.Float).SetPrec(prec), nil
+	}
+
+	// x^y = exp(y ln(x))
+	l, err := bigLog(x, work)
+	if err != nil {
+		return nil, err
+	}
+	return bigExp(l.Mul(l, y), prec), nil
+}
+
+// bigFunction applies one of the expression functions
+func bigFunction(name string, x *big.Float, prec uint) (*big.Float, error) {
+	work := prec + guardBits
+
+	switch name {
+	case "sin":
+		return bigSinCos(x, prec, true), nil
+	case "cos":
+		return bigSinCos(x, prec, false), nil
+	case "tan":
+		c := bigSinCos(x, work, false)
+		if c.Sign() == 0 {
+			return nil, errors.New("tan at a pole")
+		}
+		return new(big.Float).SetPrec(prec).Quo(bigSinCos(x, work, true), c), nil
+	case "asin":
+		return bigAsin(x, prec)
+	case "acos":
+		s, err := bigAsin(x, work)
+		if err != nil {
+			return nil, err
+		}
+		halfPi := bigPi(work)
+		halfPi.SetMantExp(halfPi, -1)
+		return new(big.Float).SetPrec(prec).Sub(halfPi, s), nil
+	case "atan":
+		return bigAtan(x, prec), nil
+	case "sinh", "cosh", "tanh":
+		ep := bigExp(x, work)
+		em := new(big.Float).SetPrec(work).Quo(bigFloat(1, work), ep)
+		num := new(big.Float).SetPrec(work).Sub(ep, em)
+		den := new(big.Float).SetPrec(work).Add(ep, em)
+		switch name {
+		case "sinh":
+			return new(big.Float).SetPrec(prec).SetMantExp(num, -1), nil
+		case "cosh":
+			return new(big.Float).SetPrec(prec).SetMantExp(den, -1), nil
+		}
+		return new(big.Float).SetPrec(prec).Quo(num, den), nil
+	case "exp":
+		return bigExp(x, prec), nil
+	case "ln", "log":
+		return bigLog(x, prec)
+	case "sqrt":
+		if x.Sign() < 0 {
+			return nil, fmt.Errorf("sqrt of %s", x.Text('g', 10))
+		}
+		return new(big.Float).SetPrec(prec).Sqrt(x), nil
+	case "abs":
+		return new(big.Float).SetPrec(prec).Abs(x), nil
+	}
+
+	return nil, fmt.Errorf("unknown function %q", name)
+}
+
+// EvalBig evaluates the expression with prec bits of mantissa. Numbers are
+// read from their text, so 0.1 and pi are as exact as prec allows.
+func (e *Expr) EvalBig(x []*big.Float, prec uint) (*big.Float, error) {
+	switch e.op {
+	case "num":
+		switch e.text {
+		case "":
+			return bigFloat(e.val, prec), nil
+		case "pi":
+			return bigPi(prec), nil
+		case "e":
+			return bigExp(bigFloat(1, prec), prec), nil
+		}
+		v, ok := new(big.Float).SetPrec(prec).SetString(e.text)
+		if !ok {
+			return nil, fmt.Errorf("invalid number %q", e.text)
+		}
+		return v, nil
+	case "var":
+		return new(big.Float).SetPrec(prec).Set(x[e.idx]), nil
+	}
+
+	args := make([]*big.Float, len(e.args))
+	for i, arg := range e.args {
+		v, err := arg.EvalBig(x, prec)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	z := new(big.Float).SetPrec(prec)
+	switch e.op {
+	case "neg":
+		return z.Neg(args[0]), nil
+	case "+":
+		return z.Add(args[0], args[1]), nil
+	case "-":
+		return z.Sub(args[0], args[1]), nil
+	case "*":
+		return z.Mul(args[0], args[1]), nil
+	case "/":
+		if args[1].Sign() == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return z.Quo(args[0], args[1]), nil
+	case "^":
+		return bigPow(args[0], args[1], prec)
+	}
+	return bigFunction(e.op, args[0], prec)
+}
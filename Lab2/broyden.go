@@ -0,0 +1,199 @@
+package main
+
+import "math"
+
+// Broyden's methods build the jacobian once and then keep it up to date with
+// rank-one updates, so each step costs a single evaluation of F. A backtracking
+// line search on ||F|| guards the step, and when it fails the jacobian is
+// rebuilt from scratch.
+
+const lineSearchHalvings = 10
+
+func BroydenGoodMethod(s System, x0 []float64, eps float64, ls LinearSolver) (SystemResult, error) {
+	n := len(x0)
+	x := make([]float64, n)
+	copy(x, x0)
+
+	fX := evalSystem(s, x)
+	jacobian := systemJacobian(s, x)
+	evaluations := 1 + jacobianCost(s, n)
+	fresh := true
+	path := [][]float64{x}
+
+	iterations := 0
+	for ; iterations < limit; iterations++ {
+		dx, err := newtonStep(ls, jacobian, fX, x)
+		if err != nil {
+			return SystemResult{}, err
+		}
+
+		next, fNext, ok, cost := lineSearch(s, x, fX, dx)
+		evaluations += cost
+		if !ok && !fresh {
+			jacobian = systemJacobian(s, x)
+			evaluations += jacobianCost(s, n)
+			fresh = true
+			continue
+		}
+
+		step := sub(next, x)
+		dF := sub(fNext, fX)
+
+		// J += (dF - J*step) * step^T / (step^T * step)
+		jStep := mulVec(jacobian, step)
+		denom := dot(step, step)
+		if denom > 0 {
+			for i := 0; i < n; i++ {
+				for j := 0; j < n; j++ {
+					jacobian[i][j] += (dF[i] - jStep[i]) * step[j] / denom
+				}
+			}
+		}
+		fresh = false
+
+		x, fX = next, fNext
+		path = append(path, x)
+		if maxAbs(step) < eps && maxAbs(dF) < eps {
+			return SystemResult{X: x, Iterations: iterations + 1, Evaluations: evaluations, Path: path}, nil
+		}
+	}
+
+	return SystemResult{}, &DivergenceError{Iterations: iterations, X: x}
+}
+
+func BroydenBadMethod(s System, x0 []float64, eps float64, ls LinearSolver) (SystemResult, error) {
+	n := len(x0)
+	x := make([]float64, n)
+	copy(x, x0)
+
+	fX := evalSystem(s, x)
+	inverse, err := inverseJacobian(s, x, ls)
+	if err != nil {
+		return SystemResult{}, err
+	}
+	evaluations := 1 + jacobianCost(s, n)
+	fresh := true
+	path := [][]float64{x}
+
+	iterations := 0
+	for ; iterations < limit; iterations++ {
+		dx := mulVec(inverse, fX)
+		for i := range dx {
+			dx[i] = -dx[i]
+		}
+
+		next, fNext, ok, cost := lineSearch(s, x, fX, dx)
+		evaluations += cost
+		if !ok && !fresh {
+			inverse, err = inverseJacobian(s, x, ls)
+			if err != nil {
+				return SystemResult{}, err
+			}
+			evaluations += jacobianCost(s, n)
+			fresh = true
+			continue
+		}
+
+		step := sub(next, x)
+		dF := sub(fNext, fX)
+
+		// H += (step - H*dF) * dF^T / (dF^T * dF)
+		hDF := mulVec(inverse, dF)
+		denom := dot(dF, dF)
+		if denom > 0 {
+			for i := 0; i < n; i++ {
+				for j := 0; j < n; j++ {
+					inverse[i][j] += (step[i] - hDF[i]) * dF[j] / denom
+				}
+			}
+		}
+		fresh = false
+
+		x, fX = next, fNext
+		path = append(path, x)
+		if maxAbs(step) < eps && maxAbs(dF) < eps {
+			return SystemResult{X: x, Iterations: iterations + 1, Evaluations: evaluations, Path: path}, nil
+		}
+	}
+
+	return SystemResult{}, &DivergenceError{Iterations: iterations, X: x}
+}
+
+// lineSearch halves the step until ||F||^2 decreases by the Armijo rule, as in
+// NewtonMethodSystem. It reports whether it
+// succeeded and how many evaluations of F it took; on failure the shortest
+// step is still returned.
+func lineSearch(s System, x, fX, dx []float64) ([]float64, []float64, bool, int) {
+	norm := dot(fX, fX)
+	t := 1.0
+	next := make([]float64, len(x))
+
+	for k := 0; ; k++ {
+		for i := range x {
+			next[i] = x[i] + t*dx[i]
+		}
+		fNext := evalSystem(s, next)
+
+		if dot(fNext, fNext) <= (1-2*armijo*t)*norm || k == lineSearchHalvings {
+			return next, fNext, k < lineSearchHalvings, k + 1
+		}
+		t /= 2
+	}
+}
+
+func inverseJacobian(s System, x []float64, ls LinearSolver) ([][]float64, error) {
+	jacobian := systemJacobian(s, x)
+	n := len(x)
+
+	inverse := make([][]float64, n)
+	for i := range inverse {
+		inverse[i] = make([]float64, n)
+	}
+
+	// column j of the inverse solves J * c = e_j
+	for j := 0; j < n; j++ {
+		e := make([]float64, n)
+		e[j] = -1
+		c, err := newtonStep(ls, jacobian, e, x)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < n; i++ {
+			inverse[i][j] = c[i]
+		}
+	}
+
+	return inverse, nil
+}
+
+func mulVec(a [][]float64, v []float64) []float64 {
+	ans := make([]float64, len(a))
+	for i := range a {
+		ans[i] = dot(a[i], v)
+	}
+	return ans
+}
+
+func dot(a, b []float64) float64 {
+	ans := 0.0
+	for i := range a {
+		ans += a[i] * b[i]
+	}
+	return ans
+}
+
+func sub(a, b []float64) []float64 {
+	ans := make([]float64, len(a))
+	for i := range a {
+		ans[i] = a[i] - b[i]
+	}
+	return ans
+}
+
+func maxAbs(v []float64) float64 {
+	max := 0.0
+	for _, item := range v {
+		max = math.Max(max, math.Abs(item))
+	}
+	return max
+}
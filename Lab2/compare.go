@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// countEvaluations wraps the equation so that every call of f and of its
+// derivatives is counted
+func countEvaluations(e Equation) (Equation, *int, *int) {
+	fCount, dCount := new(int), new(int)
+
+	f, derivative, derivative2 := e.f, e.derivative, e.derivative2
+	e.f = func(x float64) float64 {
+		*fCount++
+		return f(x)
+	}
+	e.derivative = func(x float64) float64 {
+		*dCount++
+		return derivative(x)
+	}
+	e.derivative2 = func(x float64) float64 {
+		*dCount++
+		return derivative2(x)
+	}
+
+	return e, fCount, dCount
+}
+
+// Compare runs every method on every isolated root and collects one row per
+// method and root. A failed run gets its error in the row instead of a root.
+func Compare(e Equation, methods []Method, a, b, eps float64) (Table, error) {
+	t := Table{Header: []string{"Method", "#", "X", "|f(x)|", "Iterations", "f evaluations", "f' evaluations", "Time"}}
+
+	brackets := isolateRoots(e, a, b)
+	if len(brackets) == 0 {
+		return t, fmt.Errorf("no roots in this interval")
+	}
+
+	for _, m := range methods {
+		n := 0
+		for _, br := range brackets {
+			counted, fCount, dCount := countEvaluations(e)
+
+			start := time.Now()
+			roots, _, err := findRoots(counted, m, []Bracket{br}, eps)
+			elapsed := time.Since(start)
+
+			if err != nil {
+				t.Rows = append(t.Rows, []string{m.name, "", "error: " + err.Error(), "", "", "", "", ""})
+				continue
+			}
+
+			for _, r := range roots {
+				n++
+				t.Rows = append(t.Rows, []string{
+					m.name,
+					fmt.Sprint(n),
+					fmt.Sprintf("%.15g", r.X),
+					fmt.Sprintf("%.3g", math.Abs(r.F)),
+					fmt.Sprint(r.Iterations),
+					fmt.Sprint(*fCount),
+					fmt.Sprint(*dCount),
+					elapsed.String(),
+				})
+			}
+		}
+	}
+
+	return t, nil
+}
+
+// CompareIterations puts the iteration counts of the methods side by side,
+// one row per root. It shows how much an accelerated method saves, so the
+// methods start from the whole of [a, b] and not from the narrow brackets.
+// Where a method fails on the wide interval, it is narrowed towards the
+// bracket until all of them run, the bracket itself being the last try.
+func CompareIterations(e Equation, methods []Method, brackets []Bracket, a, b, eps float64) Table {
+	t := Table{Header: []string{"Interval", "X"}}
+	for _, m := range methods {
+		t.Header = append(t.Header, m.name+" iterations")
+	}
+
+	for i, wide := range widenBrackets(brackets, a, b) {
+		br := brackets[i]
+		row, found, failed := iterationRow(e, methods, wide, eps)
+		for k := 0; failed && k < narrowSteps; k++ {
+			wide.a, wide.b = (wide.a+br.a)/2, (wide.b+br.b)/2
+			row, found, failed = iterationRow(e, methods, wide, eps)
+		}
+		if failed {
+			row, found, _ = iterationRow(e, methods, br, eps)
+		}
+
+		// a touching bracket without a root is not worth a row
+		if found || !br.touching {
+			t.Rows = append(t.Rows, row)
+		}
+	}
+
+	return t
+}
+
+// narrowSteps is how many times CompareIterations halves the distance from
+// the wide interval to the bracket before it falls back to the bracket
+const narrowSteps = 8
+
+// iterationRow runs every method on br. found tells if any of them found a
+// root and failed if any of them returned an error.
+func iterationRow(e Equation, methods []Method, br Bracket, eps float64) ([]string, bool, bool) {
+	row := []string{fmt.Sprintf("[%.6g, %.6g]", br.a, br.b), ""}
+	found, failed := false, false
+	for _, m := range methods {
+		roots, _, err := findRoots(e, m, []Bracket{br}, eps)
+		switch {
+		case err != nil:
+			failed = true
+			row = append(row, "error: "+errors.Unwrap(err).Error())
+		case len(roots) == 0:
+			row = append(row, "no root")
+		default:
+			if !found {
+				row[1] = fmt.Sprintf("%.15g", roots[0].X)
+			}
+			found = true
+			row = append(row, fmt.Sprint(roots[0].Iterations))
+		}
+	}
+	return row, found, failed
+}
+
+// widenBrackets stretches every bracket over the part of [a, b] that is
+// nearer to it than to the others. There is no root between the brackets, so
+// each one still holds only its own root.
+func widenBrackets(brackets []Bracket, a, b float64) []Bracket {
+	wide := make([]Bracket, len(brackets))
+	for i, br := range brackets {
+		wide[i] = br
+		wide[i].a, wide[i].b = a, b
+		if i > 0 {
+			wide[i].a = (brackets[i-1].b + br.a) / 2
+		}
+		if i < len(brackets)-1 {
+			wide[i].b = (br.b + brackets[i+1].a) / 2
+		}
+	}
+	return wide
+}
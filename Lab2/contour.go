@@ -0,0 +1,110 @@
+package main
+
+import (
+	"math"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+const contourGrid = 200
+
+// Contour is the zero level set of f(x, y) found by marching squares
+type Contour struct {
+	segments [][2]plotter.XY
+	draw.LineStyle
+}
+
+func NewContour(f func(x []float64) float64, xMin, xMax, yMin, yMax float64) *Contour {
+	c := &Contour{LineStyle: plotter.DefaultLineStyle}
+
+	hx := (xMax - xMin) / contourGrid
+	hy := (yMax - yMin) / contourGrid
+
+	values := make([][]float64, contourGrid+1)
+	for i := range values {
+		values[i] = make([]float64, contourGrid+1)
+		for j := range values[i] {
+			values[i][j] = f([]float64{xMin + float64(i)*hx, yMin + float64(j)*hy})
+		}
+	}
+
+	for i := 0; i < contourGrid; i++ {
+		for j := 0; j < contourGrid; j++ {
+			x0, y0 := xMin+float64(i)*hx, yMin+float64(j)*hy
+			x1, y1 := x0+hx, y0+hy
+
+			// corners counterclockwise from the bottom left one
+			v := [4]float64{values[i][j], values[i+1][j], values[i+1][j+1], values[i][j+1]}
+			corners := [4]plotter.XY{{X: x0, Y: y0}, {X: x1, Y: y0}, {X: x1, Y: y1}, {X: x0, Y: y1}}
+
+			skip := false
+			for _, item := range v {
+				if math.IsNaN(item) || math.IsInf(item, 0) {
+					skip = true
+				}
+			}
+			if skip {
+				continue
+			}
+
+			// crossings on the bottom, right, top and left edges
+			var crossings []plotter.XY
+			for k := 0; k < 4; k++ {
+				a, b := v[k], v[(k+1)%4]
+				if (a < 0) != (b < 0) {
+					t := a / (a - b)
+					p, q := corners[k], corners[(k+1)%4]
+					crossings = append(crossings, plotter.XY{X: p.X + t*(q.X-p.X), Y: p.Y + t*(q.Y-p.Y)})
+				}
+			}
+
+			switch len(crossings) {
+			case 2:
+				c.segments = append(c.segments, [2]plotter.XY{crossings[0], crossings[1]})
+			case 4:
+				// saddle: the value in the middle tells which corners are connected
+				center := (v[0] + v[1] + v[2] + v[3]) / 4
+				if (center < 0) == (v[0] < 0) {
+					c.segments = append(c.segments,
+						[2]plotter.XY{crossings[0], crossings[1]},
+						[2]plotter.XY{crossings[2], crossings[3]})
+				} else {
+					c.segments = append(c.segments,
+						[2]plotter.XY{crossings[3], crossings[0]},
+						[2]plotter.XY{crossings[1], crossings[2]})
+				}
+			}
+		}
+	}
+
+	return c
+}
+
+func (c *Contour) Plot(canvas draw.Canvas, p *plot.Plot) {
+	trX, trY := p.Transforms(&canvas)
+	for _, s := range c.segments {
+		canvas.StrokeLine2(c.LineStyle, trX(s[0].X), trY(s[0].Y), trX(s[1].X), trY(s[1].Y))
+	}
+}
+
+func (c *Contour) Thumbnail(canvas *draw.Canvas) {
+	y := canvas.Center().Y
+	canvas.StrokeLine2(c.LineStyle, canvas.Min.X, y, canvas.Max.X, y)
+}
+
+// pointsBox returns a square window around the points with some margin
+func pointsBox(points [][]float64) (float64, float64, float64, float64) {
+	xMin, xMax := points[0][0], points[0][0]
+	yMin, yMax := points[0][1], points[0][1]
+	for _, p := range points {
+		xMin, xMax = math.Min(xMin, p[0]), math.Max(xMax, p[0])
+		yMin, yMax = math.Min(yMin, p[1]), math.Max(yMax, p[1])
+	}
+
+	half := math.Max(math.Max(xMax-xMin, yMax-yMin), 2)/2 + 1
+	cx, cy := (xMin+xMax)/2, (yMin+yMax)/2
+
+	return cx - half, cx + half, cy - half, cy + half
+}
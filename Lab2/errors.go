@@ -0,0 +1,52 @@
+package main
+
+import "fmt"
+
+// ZeroDerivativeError means f'(x), or the jacobian of a system, is zero or
+// singular at X, so no Newton step can be made
+type ZeroDerivativeError struct {
+	X []float64
+}
+
+func (e *ZeroDerivativeError) Error() string {
+	return fmt.Sprintf("derivative is zero at %s", formatPoint(e.X))
+}
+
+// DivergenceError means the iterations ran out of the limit or left the
+// finite numbers
+type DivergenceError struct {
+	Iterations int
+	X          []float64
+}
+
+func (e *DivergenceError) Error() string {
+	return fmt.Sprintf("no convergence after %d iterations, last x = %s", e.Iterations, formatPoint(e.X))
+}
+
+// StagnationError means the line search could not decrease ||F||^2 even with
+// the smallest step, which usually is a local minimum of ||F|| that is not a root
+type StagnationError struct {
+	X    []float64
+	Norm float64
+}
+
+func (e *StagnationError) Error() string {
+	return fmt.Sprintf("stagnation at %s, ||F|| = %g", formatPoint(e.X), e.Norm)
+}
+
+func formatPoint(x []float64) string {
+	if len(x) == 1 {
+		return fmt.Sprint(x[0])
+	}
+	return fmt.Sprint(x)
+}
+
+// NoContractionError means phi is not a contraction on the interval,
+// q = max|phi'(x)| >= 1, so simple iteration is not guaranteed to converge
+type NoContractionError struct {
+	Q float64
+}
+
+func (e *NoContractionError) Error() string {
+	return fmt.Sprintf("max|phi'(x)| = %g >= 1, simple iteration may diverge", e.Q)
+}
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a parsed expression. Variables are x, y, z or x1, x2, ..., xn and
+// are read from the state vector by index. A number keeps its text as
+// written, or the name of the constant, for evaluation in higher precision.
+type Expr struct {
+	op   string
+	val  float64
+	text string
+	idx  int
+	args []*Expr
+}
+
+var functions = map[string]func(x float64) float64{
+	"sin":  math.Sin,
+	"cos":  math.Cos,
+	"tan":  math.Tan,
+	"asin": math.Asin,
+	"acos": math.Acos,
+	"atan": math.Atan,
+	"sinh": math.Sinh,
+	"cosh": math.Cosh,
+	"tanh": math.Tanh,
+	"exp":  math.Exp,
+	"ln":   math.Log,
+	"log":  math.Log,
+	"sqrt": math.Sqrt,
+	"abs":  math.Abs,
+}
+
+var constants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+// ParseExpr parses an expression like "x^2 + 3y - sin(x)". An equation
+// "lhs = rhs" is turned into lhs - rhs.
+func ParseExpr(s string) (*Expr, error) {
+	if sides := strings.Split(s, "="); len(sides) == 2 {
+		lhs, err := ParseExpr(sides[0])
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := ParseExpr(sides[1])
+		if err != nil {
+			return nil, err
+		}
+		return &Expr{op: "-", args: []*Expr{lhs, rhs}}, nil
+	}
+
+	p := parser{tokens: tokenize(s)}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in %q", p.tokens[p.pos], s)
+	}
+	return e, nil
+}
+
+func (e *Expr) Eval(x []float64) float64 {
+	switch e.op {
+	case "num":
+		return e.val
+	case "var":
+		return x[e.idx]
+	case "neg":
+		return -e.args[0].Eval(x)
+	case "+":
+		return e.args[0].Eval(x) + e.args[1].Eval(x)
+	case "-":
+		return e.args[0].Eval(x) - e.args[1].Eval(x)
+	case "*":
+		return e.args[0].Eval(x) * e.args[1].Eval(x)
+	case "/":
+		return e.args[0].Eval(x) / e.args[1].Eval(x)
+	case "^":
+		return math.Pow(e.args[0].Eval(x), e.args[1].Eval(x))
+	}
+	return functions[e.op](e.args[0].Eval(x))
+}
+
+// Derivative returns the expression differentiated by the unknown idx.
+// Only the obvious zeros and ones are simplified away.
+func (e *Expr) Derivative(idx int) *Expr {
+	switch e.op {
+	case "num":
+		return exprNum(0)
+	case "var":
+		if e.idx == idx {
+			return exprNum(1)
+		}
+		return exprNum(0)
+	}
+
+	u := e.args[0]
+	du := u.Derivative(idx)
+
+	switch e.op {
+	case "neg":
+		return exprNeg(du)
+	case "+":
+		return exprAdd(du, e.args[1].Derivative(idx))
+	case "-":
+		return exprSub(du, e.args[1].Derivative(idx))
+	case "*":
+		v := e.args[1]
+		return exprAdd(exprMul(du, v), exprMul(u, v.Derivative(idx)))
+	case "/":
+		v := e.args[1]
+		return exprDiv(exprSub(exprMul(du, v), exprMul(u, v.Derivative(idx))), exprCall("^", v, exprNum(2)))
+	case "^":
+		v := e.args[1]
+		if !v.dependsOn(idx) {
+			// (u^n)' = n * u^(n-1) * u'
+			n1 := exprSub(v, exprNum(1))
+			if v.op == "num" && v.val == math.Trunc(v.val) {
+				n1 = exprNum(v.val - 1)
+			}
+			return exprMul(exprMul(v, exprCall("^", u, n1)), du)
+		}
+		// (u^v)' = u^v * (v' ln(u) + v u'/u)
+		return exprMul(e, exprAdd(exprMul(v.Derivative(idx), exprCall("ln", u)), exprDiv(exprMul(v, du), u)))
+	}
+
+	var outer *Expr
+	switch e.op {
+	case "sin":
+		outer = exprCall("cos", u)
+	case "cos":
+		outer = exprNeg(exprCall("sin", u))
+	case "tan":
+		outer = exprDiv(exprNum(1), exprCall("^", exprCall("cos", u), exprNum(2)))
+	case "asin":
+		outer = exprDiv(exprNum(1), exprCall("sqrt", exprSub(exprNum(1), exprCall("^", u, exprNum(2)))))
+	case "acos":
+		outer = exprNeg(exprDiv(exprNum(1), exprCall("sqrt", exprSub(exprNum(1), exprCall("^", u, exprNum(2))))))
+	case "atan":
+		outer = exprDiv(exprNum(1), exprAdd(exprNum(1), exprCall("^", u, exprNum(2))))
+	case "sinh":
+		outer = exprCall("cosh", u)
+	case "cosh":
+		outer = exprCall("sinh", u)
+	case "tanh":
+		outer = exprDiv(exprNum(1), exprCall("^", exprCall("cosh", u), exprNum(2)))
+	case "exp":
+		outer = e
+	case "ln", "log":
+		outer = exprDiv(exprNum(1), u)
+	case "sqrt":
+		outer = exprDiv(exprNum(1), exprMul(exprNum(2), e))
+	case "abs":
+		outer = exprDiv(u, e)
+	}
+	return exprMul(outer, du)
+}
+
+func (e *Expr) dependsOn(idx int) bool {
+	if e.op == "var" {
+		return e.idx == idx
+	}
+	for _, arg := range e.args {
+		if arg.dependsOn(idx) {
+			return true
+		}
+	}
+	return false
+}
+
+func exprNum(v float64) *Expr {
+	return &Expr{op: "num", val: v}
+}
+
+func exprCall(op string, args ...*Expr) *Expr {
+	return &Expr{op: op, args: args}
+}
+
+func isExprNum(e *Expr, v float64) bool {
+	return e.op == "num" && e.val == v
+}
+
+func exprNeg(a *Expr) *Expr {
+	if isExprNum(a, 0) {
+		return a
+	}
+	return exprCall("neg", a)
+}
+
+func exprAdd(a, b *Expr) *Expr {
+	switch {
+	case isExprNum(a, 0):
+		return b
+	case isExprNum(b, 0):
+		return a
+	}
+	return exprCall("+", a, b)
+}
+
+func exprSub(a, b *Expr) *Expr {
+	switch {
+	case isExprNum(b, 0):
+		return a
+	case isExprNum(a, 0):
+		return exprNeg(b)
+	}
+	return exprCall("-", a, b)
+}
+
+func exprMul(a, b *Expr) *Expr {
+	switch {
+	case isExprNum(a, 0) || isExprNum(b, 0):
+		return exprNum(0)
+	case isExprNum(a, 1):
+		return b
+	case isExprNum(b, 1):
+		return a
+	}
+	return exprCall("*", a, b)
+}
+
+func exprDiv(a, b *Expr) *Expr {
+	if isExprNum(b, 1) || isExprNum(a, 0) {
+		return a
+	}
+	return exprCall("/", a, b)
+}
+
+// NumVars returns the number of unknowns the expression refers to
+func (e *Expr) NumVars() int {
+	n := 0
+	if e.op == "var" {
+		n = e.idx + 1
+	}
+	for _, arg := range e.args {
+		if m := arg.NumVars(); m > n {
+			n = m
+		}
+	}
+	return n
+}
+
+func tokenize(s string) []string {
+	var tokens []string
+	r := []rune(s)
+
+	for i := 0; i < len(r); {
+		switch {
+		case unicode.IsSpace(r[i]):
+			i++
+		case unicode.IsDigit(r[i]) || r[i] == '.':
+			j := i
+			for j < len(r) && (unicode.IsDigit(r[j]) || r[j] == '.') {
+				j++
+			}
+			// exponent part, e.g. 1e-3
+			if j+1 < len(r) && (r[j] == 'e' || r[j] == 'E') &&
+				(unicode.IsDigit(r[j+1]) || (j+2 < len(r) && (r[j+1] == '-' || r[j+1] == '+') && unicode.IsDigit(r[j+2]))) {
+				j += 2
+				for j < len(r) && unicode.IsDigit(r[j]) {
+					j++
+				}
+			}
+			tokens = append(tokens, string(r[i:j]))
+			i = j
+		case unicode.IsLetter(r[i]):
+			j := i
+			for j < len(r) && (unicode.IsLetter(r[j]) || unicode.IsDigit(r[j])) {
+				j++
+			}
+			tokens = append(tokens, string(r[i:j]))
+			i = j
+		default:
+			tokens = append(tokens, string(r[i]))
+			i++
+		}
+	}
+
+	return tokens
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *parser) expr() (*Expr, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "+" || p.peek() == "-" {
+		op := p.next()
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = &Expr{op: op, args: []*Expr{left, right}}
+	}
+
+	return left, nil
+}
+
+func (p *parser) term() (*Expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op := p.peek()
+		switch {
+		case op == "*" || op == "/":
+			p.next()
+		case op == "(" || (op != "" && (unicode.IsLetter([]rune(op)[0]) || unicode.IsDigit([]rune(op)[0]))):
+			// implicit multiplication, e.g. 2x or 3(x + 1)
+			op = "*"
+		default:
+			return left, nil
+		}
+
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &Expr{op: op, args: []*Expr{left, right}}
+	}
+}
+
+func (p *parser) unary() (*Expr, error) {
+	switch p.peek() {
+	case "-":
+		p.next()
+		arg, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &Expr{op: "neg", args: []*Expr{arg}}, nil
+	case "+":
+		p.next()
+		return p.unary()
+	}
+	return p.power()
+}
+
+func (p *parser) power() (*Expr, error) {
+	base, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek() == "^" {
+		p.next()
+		exp, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &Expr{op: "^", args: []*Expr{base, exp}}, nil
+	}
+
+	return base, nil
+}
+
+func (p *parser) primary() (*Expr, error) {
+	t := p.next()
+
+	switch {
+	case t == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case t == "(":
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return e, nil
+	case unicode.IsDigit([]rune(t)[0]) || t[0] == '.':
+		v, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t)
+		}
+		return &Expr{op: "num", val: v, text: t}, nil
+	case unicode.IsLetter([]rune(t)[0]):
+		name := strings.ToLower(t)
+
+		if _, ok := functions[name]; ok {
+			if p.next() != "(" {
+				return nil, fmt.Errorf("missing ( after %s", name)
+			}
+			arg, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if p.next() != ")" {
+				return nil, fmt.Errorf("missing )")
+			}
+			return &Expr{op: name, args: []*Expr{arg}}, nil
+		}
+
+		if v, ok := constants[name]; ok {
+			return &Expr{op: "num", val: v, text: name}, nil
+		}
+
+		if idx, ok := variableIndex(name); ok {
+			return &Expr{op: "var", idx: idx}, nil
+		}
+
+		return nil, fmt.Errorf("unknown name %q", t)
+	}
+
+	return nil, fmt.Errorf("unexpected %q", t)
+}
+
+func variableIndex(name string) (int, bool) {
+	switch name {
+	case "x":
+		return 0, true
+	case "y":
+		return 1, true
+	case "z":
+		return 2, true
+	}
+
+	if strings.HasPrefix(name, "x") {
+		if i, err := strconv.Atoi(name[1:]); err == nil && i >= 1 {
+			return i - 1, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/cmplx"
+	"os"
+	"sync"
+)
+
+// Newton fractal: every pixel is a starting point of Newton's method in the
+// complex plane, colored by the root it goes to. The brighter the pixel the
+// faster it converged, black ones did not converge at all.
+
+const (
+	fractalLimit     = 64
+	fractalTolerance = 1e-6
+	// after this many iterations a pixel is as dark as it gets
+	shadeIterations = 25
+)
+
+var rootColors = []color.RGBA{
+	{R: 230, G: 60, B: 60, A: 255},
+	{R: 60, G: 180, B: 75, A: 255},
+	{R: 60, G: 100, B: 230, A: 255},
+	{R: 240, G: 200, B: 40, A: 255},
+	{R: 145, G: 30, B: 180, A: 255},
+	{R: 70, G: 220, B: 220, A: 255},
+	{R: 240, G: 50, B: 230, A: 255},
+	{R: 245, G: 130, B: 48, A: 255},
+}
+
+// NewtonFractal renders width x height pixels of the square centered at 0
+// with the given half side, stretched along the longer side of the image.
+// Rows are shared out between the workers.
+func NewtonFractal(p Polynomial, roots []PolynomialRoot, width, height int, radius float64, workers int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	d := p.Derivative()
+
+	scale := 2 * radius / float64(width)
+	if height > width {
+		scale = 2 * radius / float64(height)
+	}
+
+	rows := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range rows {
+				im := (float64(height)/2 - float64(j) - 0.5) * scale
+				for i := 0; i < width; i++ {
+					re := (float64(i) + 0.5 - float64(width)/2) * scale
+					img.SetRGBA(i, j, fractalColor(p, d, roots, complex(re, im)))
+				}
+			}
+		}()
+	}
+
+	for j := 0; j < height; j++ {
+		rows <- j
+	}
+	close(rows)
+	wg.Wait()
+
+	return img
+}
+
+func fractalColor(p, d Polynomial, roots []PolynomialRoot, z complex128) color.RGBA {
+	for k := 0; k < fractalLimit; k++ {
+		for i, r := range roots {
+			// multiple roots are reached only linearly, so they get a wider target
+			if cmplx.Abs(z-r.Z) < fractalTolerance*float64(1000*(r.Multiplicity-1)+1) {
+				return shade(rootColors[i%len(rootColors)], k)
+			}
+		}
+
+		dz := p.Eval(z) / d.Eval(z)
+		if cmplx.IsNaN(dz) || cmplx.IsInf(dz) {
+			break
+		}
+		z -= dz
+	}
+
+	return color.RGBA{A: 255}
+}
+
+func shade(c color.RGBA, iterations int) color.RGBA {
+	t := math.Max(0.2, 1-float64(iterations)/shadeIterations)
+	return color.RGBA{
+		R: uint8(float64(c.R) * t),
+		G: uint8(float64(c.G) * t),
+		B: uint8(float64(c.B) * t),
+		A: 255,
+	}
+}
+
+// fractalRadius is the half side of a window that shows every root
+func fractalRadius(roots []PolynomialRoot) float64 {
+	radius := 1.0
+	for _, r := range roots {
+		radius = math.Max(radius, 1.5*cmplx.Abs(r.Z))
+	}
+	return radius
+}
+
+func saveFractal(img image.Image, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return png.Encode(file, img)
+}
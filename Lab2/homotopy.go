@@ -0,0 +1,289 @@
+package main
+
+import (
+	"errors"
+	"math"
+)
+
+// The Newton homotopy H(x, t) = F(x) - (1 - t) * F(x0) turns the system
+// F(x) = F(x0), solved by x0, at t = 0 into F(x) = 0 at t = 1. Its zeros form
+// a path through (x0, 0) that is followed by arc length, so it may turn back
+// in t. Each step predicts along the tangent and corrects with Newton's method
+// on H together with the condition that the step stays orthogonal to the
+// tangent. Every time the path crosses t = 1 there is a solution.
+
+const (
+	homotopyStep    = 0.1
+	homotopyMaxStep = 1.0
+	homotopyMinStep = 1e-8
+	// the path is given up once it gets this far, relative to x0
+	homotopyRadius = 1e3
+	correctorSteps = 6
+	// the tangent may turn by at most about 35 degrees in one step
+	homotopyCos = 0.8
+)
+
+// homotopyPath is what one direction of the path from (x0, 0) gave
+type homotopyPath struct {
+	points        [][]float64
+	solutions     [][]float64
+	turningPoints [][]float64
+	steps         int
+	evaluations   int
+	closed        bool
+}
+
+// HomotopyMethod follows the path from x0 in the direction of growing t. A
+// path that does not come back to x0 is followed the other way as well,
+// since it may reach t = 1 only after turning back.
+func HomotopyMethod(s System, x0 []float64, eps float64, ls LinearSolver) (SystemResult, error) {
+	if ls == nil {
+		ls = GaussSolver{}
+	}
+
+	f0 := evalSystem(s, x0)
+	forward, err := followPath(s, f0, x0, 1, eps, ls)
+	if err != nil {
+		return SystemResult{}, err
+	}
+
+	r := SystemResult{
+		Path:          forward.points,
+		Solutions:     forward.solutions,
+		TurningPoints: forward.turningPoints,
+		Iterations:    forward.steps,
+		Evaluations:   1 + forward.evaluations,
+	}
+
+	if !forward.closed {
+		backward, err := followPath(s, f0, x0, -1, eps, ls)
+		if err != nil {
+			return SystemResult{}, err
+		}
+
+		// one line from the far end of the backward path through x0
+		var path [][]float64
+		for i := len(backward.points) - 1; i > 0; i-- {
+			path = append(path, backward.points[i])
+		}
+		r.Path = append(path, r.Path...)
+
+		for _, x := range backward.solutions {
+			if !containsPoint(r.Solutions, x, 10*eps) {
+				r.Solutions = append(r.Solutions, x)
+			}
+		}
+		// a turning point at x0 itself is found both ways
+		for _, y := range backward.turningPoints {
+			if !containsPoint(r.TurningPoints, y, 1e-6*math.Max(1, maxAbs(x0))) {
+				r.TurningPoints = append(r.TurningPoints, y)
+			}
+		}
+		r.Iterations += backward.steps
+		r.Evaluations += backward.evaluations
+	}
+
+	if len(r.Solutions) == 0 {
+		return SystemResult{}, &DivergenceError{Iterations: r.Iterations, X: r.Path[len(r.Path)-1]}
+	}
+	r.X = r.Solutions[0]
+	return r, nil
+}
+
+// followPath starts at (x0, 0) with t growing when direction is 1 and
+// falling when it is -1. It stops when the path closes, leaves the radius,
+// or the step gets too small.
+func followPath(s System, f0, x0 []float64, direction float64, eps float64, ls LinearSolver) (homotopyPath, error) {
+	n := len(x0)
+	radius := homotopyRadius * math.Max(1, maxAbs(x0))
+	p := homotopyPath{points: [][]float64{x0}}
+
+	start := append(append([]float64{}, x0...), 0)
+	y := start
+	v, err := startTangent(s, f0, y, direction, ls)
+	p.evaluations += jacobianCost(s, n)
+	if err != nil {
+		return p, err
+	}
+
+	h, length := homotopyStep, 0.0
+	for ; p.steps < limit; p.steps++ {
+		next, cost, corrections, ok := pathCorrector(s, f0, y, v, h, ls)
+		p.evaluations += cost
+
+		var vNext []float64
+		if ok {
+			vNext, err = pathTangent(s, f0, next, v, ls)
+			p.evaluations += jacobianCost(s, n)
+			ok = err == nil && dot(v, vNext) > homotopyCos
+		}
+		if !ok {
+			h /= 2
+			if h < homotopyMinStep {
+				break
+			}
+			continue
+		}
+
+		if (y[n]-1)*(next[n]-1) <= 0 {
+			x, cost, ok := polishSolution(s, interpolate(y, next, (1-y[n])/(next[n]-y[n]))[:n], eps, ls)
+			p.evaluations += cost
+			if ok && !containsPoint(p.solutions, x, 10*eps) {
+				p.solutions = append(p.solutions, x)
+			}
+		}
+
+		if (v[n] > 0) != (vNext[n] > 0) {
+			p.turningPoints = append(p.turningPoints, interpolate(y, next, v[n]/(v[n]-vNext[n])))
+		}
+
+		// back at (x0, 0), the path is a loop and repeats itself
+		length += h
+		p.closed = length > 3*h && maxAbs(sub(next, start)) < h
+
+		y, v = next, vNext
+		p.points = append(p.points, y[:n])
+		if p.closed || maxAbs(y) > radius {
+			break
+		}
+
+		// far from the origin the path is straight on the scale of |y|
+		if corrections <= 3 {
+			h = math.Min(2*h, math.Max(homotopyMaxStep, 0.1*maxAbs(y)))
+		}
+	}
+
+	return p, nil
+}
+
+// startTangent orients the first tangent by the sign of t. When the jacobian
+// at x0 is singular the path may start with t standing still, then it is
+// oriented by the first unknown that moves.
+func startTangent(s System, f0, y []float64, direction float64, ls LinearSolver) ([]float64, error) {
+	var err error
+	for k := len(y) - 1; k >= 0; k-- {
+		e := make([]float64, len(y))
+		e[k] = direction
+
+		var v []float64
+		if v, err = pathTangent(s, f0, y, e, ls); err == nil {
+			return v, nil
+		}
+	}
+	return nil, err
+}
+
+// pathTangent returns the unit vector v with H'(y) * v = 0 that points the
+// same way as the previous tangent
+func pathTangent(s System, f0, y, previous []float64, ls LinearSolver) ([]float64, error) {
+	n := len(f0)
+	b := make([]float64, n+1)
+	b[n] = 1
+
+	w, err := ls.Solve(borderedJacobian(s, f0, y, previous), b)
+	if errors.Is(err, ErrSingular) {
+		return nil, &ZeroDerivativeError{X: y[:n]}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	norm := math.Sqrt(dot(w, w))
+	for i := range w {
+		w[i] /= norm
+	}
+	return w, nil
+}
+
+// pathCorrector steps h along the tangent v and brings the point back to the
+// path. It returns the point, the evaluations of F it took, the number of
+// corrections and whether they converged close enough to the prediction.
+func pathCorrector(s System, f0, y, v []float64, h float64, ls LinearSolver) ([]float64, int, int, bool) {
+	n := len(f0)
+	predicted := make([]float64, n+1)
+	for i := range predicted {
+		predicted[i] = y[i] + h*v[i]
+	}
+	z := append([]float64{}, predicted...)
+
+	evaluations := 0
+	for k := 1; k <= correctorSteps; k++ {
+		fZ := evalSystem(s, z[:n])
+		evaluations += 1 + jacobianCost(s, n)
+
+		b := make([]float64, n+1)
+		for i := 0; i < n; i++ {
+			b[i] = -(fZ[i] - (1-z[n])*f0[i])
+		}
+		b[n] = -dot(v, sub(z, predicted))
+
+		dz, err := ls.Solve(borderedJacobian(s, f0, z, v), b)
+		if err != nil {
+			return nil, evaluations, k, false
+		}
+		for i := range z {
+			z[i] += dz[i]
+			if math.IsNaN(z[i]) || math.IsInf(z[i], 0) {
+				return nil, evaluations, k, false
+			}
+		}
+
+		if maxAbs(dz) < 1e-10*(1+maxAbs(z)) {
+			return z, evaluations, k, maxAbs(sub(z, predicted)) < h/2
+		}
+	}
+
+	return nil, evaluations, correctorSteps, false
+}
+
+// borderedJacobian is the n x (n+1) jacobian of H with the row v below it
+func borderedJacobian(s System, f0, y, v []float64) [][]float64 {
+	n := len(f0)
+	jacobian := systemJacobian(s, y[:n])
+
+	a := make([][]float64, n+1)
+	for i := 0; i < n; i++ {
+		a[i] = append(append([]float64{}, jacobian[i]...), f0[i])
+	}
+	a[n] = v
+	return a
+}
+
+// polishSolution finishes a crossing of t = 1 with plain Newton's steps on F
+func polishSolution(s System, x []float64, eps float64, ls LinearSolver) ([]float64, int, bool) {
+	n := len(x)
+	evaluations := 0
+	for k := 0; k < 2*correctorSteps; k++ {
+		dx, err := newtonStep(ls, systemJacobian(s, x), evalSystem(s, x), x)
+		evaluations += 1 + jacobianCost(s, n)
+		if err != nil {
+			return nil, evaluations, false
+		}
+
+		for i := range x {
+			x[i] += dx[i]
+		}
+		if maxAbs(dx) < eps {
+			return x, evaluations, true
+		}
+	}
+	return nil, evaluations, false
+}
+
+// interpolate returns a + w * (b - a)
+func interpolate(a, b []float64, w float64) []float64 {
+	ans := make([]float64, len(a))
+	for i := range a {
+		ans[i] = a[i] + w*(b[i]-a[i])
+	}
+	return ans
+}
+
+func containsPoint(points [][]float64, x []float64, tolerance float64) bool {
+	for _, p := range points {
+		if maxAbs(sub(p, x)) < tolerance {
+			return true
+		}
+	}
+	return false
+}
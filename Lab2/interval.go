@@ -0,0 +1,351 @@
+package main
+
+import "math"
+
+// Interval [Lo, Hi] of real numbers. Every operation rounds its bounds
+// outward, so the result always contains the exact value of the operation on
+// any points of the arguments. NaN bounds mean nothing is known.
+//
+// For + - * / the rounding error is recovered exactly (TwoSum, FMA), so a
+// bound moves to the next float only when the operation was inexact.
+type Interval struct {
+	Lo, Hi float64
+}
+
+// libraryUlps is how far the results of the math package functions are
+// widened. Unlike + - * / they are not correctly rounded, but stay within
+// one or two ulps of the exact value.
+const libraryUlps = 2
+
+var entire = Interval{math.Inf(-1), math.Inf(1)}
+
+func point(x float64) Interval {
+	return Interval{x, x}
+}
+
+func outward(lo, hi float64) Interval {
+	return Interval{math.Nextafter(lo, math.Inf(-1)), math.Nextafter(hi, math.Inf(1))}
+}
+
+func outwardLibrary(lo, hi float64) Interval {
+	for i := 0; i < libraryUlps; i++ {
+		lo, hi = math.Nextafter(lo, math.Inf(-1)), math.Nextafter(hi, math.Inf(1))
+	}
+	return Interval{lo, hi}
+}
+
+func (x Interval) Width() float64 {
+	return x.Hi - x.Lo
+}
+
+func (x Interval) Mid() float64 {
+	return x.Lo + (x.Hi-x.Lo)/2
+}
+
+// Excludes reports whether v is surely not in x
+func (x Interval) Excludes(v float64) bool {
+	return x.Lo > v || x.Hi < v
+}
+
+func (x Interval) IsValid() bool {
+	return !math.IsNaN(x.Lo) && !math.IsNaN(x.Hi) && x.Lo <= x.Hi
+}
+
+func (x Interval) Intersect(y Interval) (Interval, bool) {
+	z := Interval{math.Max(x.Lo, y.Lo), math.Min(x.Hi, y.Hi)}
+	return z, z.Lo <= z.Hi
+}
+
+func (x Interval) Neg() Interval {
+	return Interval{-x.Hi, -x.Lo}
+}
+
+func (x Interval) Add(y Interval) Interval {
+	lo, _ := sumBounds(x.Lo, y.Lo)
+	_, hi := sumBounds(x.Hi, y.Hi)
+	return Interval{lo, hi}
+}
+
+func (x Interval) Sub(y Interval) Interval {
+	return x.Add(y.Neg())
+}
+
+func (x Interval) Mul(y Interval) Interval {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, a := range [2]float64{x.Lo, x.Hi} {
+		for _, b := range [2]float64{y.Lo, y.Hi} {
+			pLo, pHi := productBounds(a, b)
+			if math.IsNaN(pLo) {
+				// 0 * inf
+				return entire
+			}
+			lo, hi = math.Min(lo, pLo), math.Max(hi, pHi)
+		}
+	}
+	return Interval{lo, hi}
+}
+
+func (x Interval) Div(y Interval) Interval {
+	if !y.Excludes(0) {
+		return entire
+	}
+	lo, _ := reciprocalBounds(y.Hi)
+	_, hi := reciprocalBounds(y.Lo)
+	return x.Mul(Interval{lo, hi})
+}
+
+// sumBounds returns the floats just below and above the exact a + b
+func sumBounds(a, b float64) (float64, float64) {
+	s := a + b
+	if math.IsInf(s, 0) || math.IsNaN(s) {
+		return overflowBounds(s, a, b)
+	}
+	bb := s - a
+	return directed(s, (a-(s-bb))+(b-bb))
+}
+
+// productBounds returns the floats just below and above the exact a * b
+func productBounds(a, b float64) (float64, float64) {
+	p := a * b
+	if math.IsInf(p, 0) || math.IsNaN(p) {
+		return overflowBounds(p, a, b)
+	}
+	if p != 0 && math.Abs(p) < 1e-290 {
+		// the error of a tiny product may be lost to underflow
+		return math.Nextafter(p, math.Inf(-1)), math.Nextafter(p, math.Inf(1))
+	}
+	if p == 0 && a != 0 && b != 0 {
+		return -math.SmallestNonzeroFloat64, math.SmallestNonzeroFloat64
+	}
+	return directed(p, math.FMA(a, b, -p))
+}
+
+// reciprocalBounds returns the floats just below and above the exact 1/y
+func reciprocalBounds(y float64) (float64, float64) {
+	q := 1 / y
+	if math.IsInf(q, 0) || q == 0 {
+		return math.Nextafter(q, math.Inf(-1)), math.Nextafter(q, math.Inf(1))
+	}
+	// 1/y = q - r/y exactly
+	r := math.FMA(q, y, -1)
+	return directed(q, -r/y)
+}
+
+// directed turns a result and the sign of its rounding error (exact value
+// minus the result) into bounds of the exact value
+func directed(v, err float64) (float64, float64) {
+	switch {
+	case err > 0:
+		return v, math.Nextafter(v, math.Inf(1))
+	case err < 0:
+		return math.Nextafter(v, math.Inf(-1)), v
+	}
+	return v, v
+}
+
+// overflowBounds handles a result that is infinite or NaN. An infinity that
+// came from finite arguments is an overflow, the exact value is still finite.
+func overflowBounds(v, a, b float64) (float64, float64) {
+	if math.IsNaN(v) || math.IsInf(a, 0) || math.IsInf(b, 0) {
+		return v, v
+	}
+	if v > 0 {
+		return math.MaxFloat64, v
+	}
+	return v, -math.MaxFloat64
+}
+
+// Pow handles an integer exponent by repeated multiplication, taking the
+// sign of the base into account, anything else as exp(y*ln(x))
+func (x Interval) Pow(y Interval) Interval {
+	if y.Lo != y.Hi || y.Lo != math.Trunc(y.Lo) || math.Abs(y.Lo) > 1<<20 {
+		return y.Mul(x.Log()).Exp()
+	}
+
+	n := int(y.Lo)
+	switch {
+	case n == 0:
+		return point(1)
+	case n < 0:
+		return point(1).Div(x.Pow(point(float64(-n))))
+	case n%2 == 1 || x.Lo >= 0:
+		// monotone increasing
+		return Interval{powBounds(x.Lo, n).Lo, powBounds(x.Hi, n).Hi}
+	case x.Hi <= 0:
+		return Interval{powBounds(x.Hi, n).Lo, powBounds(x.Lo, n).Hi}
+	}
+	return Interval{0, powBounds(math.Max(-x.Lo, x.Hi), n).Hi}
+}
+
+// powBounds encloses v^n, n > 0, by squaring
+func powBounds(v float64, n int) Interval {
+	ans, base := point(1), point(v)
+	for ; n > 0; n /= 2 {
+		if n%2 == 1 {
+			ans = ans.Mul(base)
+		}
+		base = base.Mul(base)
+	}
+	return ans
+}
+
+func (x Interval) Exp() Interval {
+	return outwardLibrary(math.Exp(x.Lo), math.Exp(x.Hi)).clip(0, math.Inf(1))
+}
+
+// Log keeps only the part of x where the logarithm is defined
+func (x Interval) Log() Interval {
+	if x.Hi <= 0 {
+		return Interval{math.NaN(), math.NaN()}
+	}
+	if x.Lo <= 0 {
+		return outwardLibrary(math.Inf(-1), math.Log(x.Hi))
+	}
+	return outwardLibrary(math.Log(x.Lo), math.Log(x.Hi))
+}
+
+func (x Interval) Sqrt() Interval {
+	if x.Hi < 0 {
+		return Interval{math.NaN(), math.NaN()}
+	}
+	return outwardLibrary(math.Sqrt(math.Max(x.Lo, 0)), math.Sqrt(x.Hi)).clip(0, math.Inf(1))
+}
+
+func (x Interval) Abs() Interval {
+	switch {
+	case x.Lo >= 0:
+		return x
+	case x.Hi <= 0:
+		return x.Neg()
+	}
+	return Interval{0, math.Max(-x.Lo, x.Hi)}
+}
+
+// Sin takes the extrema inside x into account. They are at pi/2 + k*pi,
+// checked with some slack, since math.Pi is not exactly pi, which can only
+// make the result wider.
+func (x Interval) Sin() Interval {
+	lo, hi := math.Sin(x.Lo), math.Sin(x.Hi)
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	if x.reaches(math.Pi/2, 2*math.Pi) {
+		hi = 1
+	}
+	if x.reaches(-math.Pi/2, 2*math.Pi) {
+		lo = -1
+	}
+	return outwardLibrary(lo, hi).clip(-1, 1)
+}
+
+func (x Interval) Cos() Interval {
+	lo, hi := math.Cos(x.Lo), math.Cos(x.Hi)
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	if x.reaches(0, 2*math.Pi) {
+		hi = 1
+	}
+	if x.reaches(math.Pi, 2*math.Pi) {
+		lo = -1
+	}
+	return outwardLibrary(lo, hi).clip(-1, 1)
+}
+
+func (x Interval) Tan() Interval {
+	if x.reaches(math.Pi/2, math.Pi) {
+		return entire
+	}
+	return outwardLibrary(math.Tan(x.Lo), math.Tan(x.Hi))
+}
+
+func (x Interval) Asin() Interval {
+	if x.Lo > 1 || x.Hi < -1 {
+		return Interval{math.NaN(), math.NaN()}
+	}
+	return outwardLibrary(math.Asin(math.Max(x.Lo, -1)), math.Asin(math.Min(x.Hi, 1)))
+}
+
+func (x Interval) Acos() Interval {
+	if x.Lo > 1 || x.Hi < -1 {
+		return Interval{math.NaN(), math.NaN()}
+	}
+	return outwardLibrary(math.Acos(math.Min(x.Hi, 1)), math.Acos(math.Max(x.Lo, -1))).clip(0, math.Inf(1))
+}
+
+func (x Interval) Atan() Interval {
+	return outwardLibrary(math.Atan(x.Lo), math.Atan(x.Hi))
+}
+
+func (x Interval) Sinh() Interval {
+	return outwardLibrary(math.Sinh(x.Lo), math.Sinh(x.Hi))
+}
+
+func (x Interval) Cosh() Interval {
+	a := x.Abs()
+	return outwardLibrary(math.Cosh(a.Lo), math.Cosh(a.Hi)).clip(1, math.Inf(1))
+}
+
+func (x Interval) Tanh() Interval {
+	return outwardLibrary(math.Tanh(x.Lo), math.Tanh(x.Hi)).clip(-1, 1)
+}
+
+// reaches reports whether x may contain c + k*period for some integer k
+func (x Interval) reaches(c, period float64) bool {
+	if x.Width() >= period || math.IsInf(x.Lo, 0) || math.IsInf(x.Hi, 0) {
+		return true
+	}
+	slack := 1e-12 * math.Max(1, math.Max(math.Abs(x.Lo), math.Abs(x.Hi)))
+	k := math.Ceil((x.Lo - slack - c) / period)
+	return c+k*period <= x.Hi+slack
+}
+
+// clip drops the part of x outside of the range of the function
+func (x Interval) clip(lo, hi float64) Interval {
+	return Interval{math.Max(x.Lo, lo), math.Min(x.Hi, hi)}
+}
+
+var intervalFunctions = map[string]func(x Interval) Interval{
+	"sin":  Interval.Sin,
+	"cos":  Interval.Cos,
+	"tan":  Interval.Tan,
+	"asin": Interval.Asin,
+	"acos": Interval.Acos,
+	"atan": Interval.Atan,
+	"sinh": Interval.Sinh,
+	"cosh": Interval.Cosh,
+	"tanh": Interval.Tanh,
+	"exp":  Interval.Exp,
+	"ln":   Interval.Log,
+	"log":  Interval.Log,
+	"sqrt": Interval.Sqrt,
+	"abs":  Interval.Abs,
+}
+
+// EvalInterval encloses the values of the expression over the box x.
+// Numbers that are not integers went through decimal to binary rounding,
+// so they are widened as well.
+func (e *Expr) EvalInterval(x []Interval) Interval {
+	switch e.op {
+	case "num":
+		if e.val == math.Trunc(e.val) && math.Abs(e.val) < 1<<53 {
+			return point(e.val)
+		}
+		return outward(e.val, e.val)
+	case "var":
+		return x[e.idx]
+	case "neg":
+		return e.args[0].EvalInterval(x).Neg()
+	case "+":
+		return e.args[0].EvalInterval(x).Add(e.args[1].EvalInterval(x))
+	case "-":
+		return e.args[0].EvalInterval(x).Sub(e.args[1].EvalInterval(x))
+	case "*":
+		return e.args[0].EvalInterval(x).Mul(e.args[1].EvalInterval(x))
+	case "/":
+		return e.args[0].EvalInterval(x).Div(e.args[1].EvalInterval(x))
+	case "^":
+		return e.args[0].EvalInterval(x).Pow(e.args[1].EvalInterval(x))
+	}
+	return intervalFunctions[e.op](e.args[0].EvalInterval(x))
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+// exact turns a float64 into a big.Float wide enough to hold sums and
+// products of two of them without rounding
+func exact(x float64) *big.Float {
+	return new(big.Float).SetPrec(4096).SetFloat64(x)
+}
+
+// checkBounds fails unless lo <= v <= hi, and, when tight, lo and hi are
+// the same or neighbouring floats
+func checkBounds(t *testing.T, name string, v *big.Float, lo, hi float64, tight bool) {
+	t.Helper()
+	if exact(lo).Cmp(v) > 0 || exact(hi).Cmp(v) < 0 {
+		t.Errorf("%s: [%g, %g] does not hold %s", name, lo, hi, v.Text('g', 20))
+	}
+	if tight && hi > math.Nextafter(lo, math.Inf(1)) {
+		t.Errorf("%s: [%g, %g] is wider than one ulp", name, lo, hi)
+	}
+}
+
+func TestSumBounds(t *testing.T) {
+	tests := []struct {
+		a, b  float64
+		tight bool
+	}{
+		{0.1, 0.2, true},
+		{1, 1e-17, true},
+		{3, -1e-20, true},
+		{-0.1, 0.1, true},
+		{1e308, 1e308, false},
+		{-1e308, -1e308, false},
+	}
+
+	for _, tt := range tests {
+		lo, hi := sumBounds(tt.a, tt.b)
+		sum := new(big.Float).Add(exact(tt.a), exact(tt.b))
+		checkBounds(t, "sumBounds", sum, lo, hi, tt.tight)
+	}
+}
+
+func TestProductBounds(t *testing.T) {
+	tests := []struct {
+		a, b  float64
+		tight bool
+	}{
+		{0.1, 0.3, true},
+		{-3, 1.0 / 3, true},
+		// below 1e-290 the bounds are widened both ways
+		{1e-160, 1e-140, false},
+		{1e-200, 1e-200, false},
+		{1e200, 1e200, false},
+		{-1e200, 1e200, false},
+	}
+
+	for _, tt := range tests {
+		lo, hi := productBounds(tt.a, tt.b)
+		product := new(big.Float).Mul(exact(tt.a), exact(tt.b))
+		checkBounds(t, "productBounds", product, lo, hi, tt.tight)
+	}
+}
+
+func TestReciprocalBounds(t *testing.T) {
+	for _, y := range []float64{3, -3, 0.1, -0.7, 1e-310, 1e300} {
+		lo, hi := reciprocalBounds(y)
+
+		// lo <= 1/y <= hi is lo*y <= 1 <= hi*y, turned around for y < 0
+		one := exact(1)
+		loY := new(big.Float).Mul(exact(lo), exact(y))
+		hiY := new(big.Float).Mul(exact(hi), exact(y))
+		if y < 0 {
+			loY, hiY = hiY, loY
+		}
+		if loY.Cmp(one) > 0 || hiY.Cmp(one) < 0 {
+			t.Errorf("reciprocalBounds(%g): [%g, %g] does not hold 1/y", y, lo, hi)
+		}
+		if !math.IsInf(hi, 0) && hi > math.Nextafter(lo, math.Inf(1)) {
+			t.Errorf("reciprocalBounds(%g): [%g, %g] is wider than one ulp", y, lo, hi)
+		}
+	}
+}
+
+func TestSinCos(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(x Interval) Interval
+		g    func(x float64) float64
+		x    Interval
+		// the extrema the result must reach
+		min, max bool
+	}{
+		{"sin", Interval.Sin, math.Sin, Interval{0, 2}, false, true},
+		{"sin", Interval.Sin, math.Sin, Interval{4, 5}, true, false},
+		{"sin", Interval.Sin, math.Sin, Interval{-10, 10}, true, true},
+		{"sin", Interval.Sin, math.Sin, Interval{0.1, 1}, false, false},
+		{"sin", Interval.Sin, math.Sin, point(math.Pi / 2), false, true},
+		{"sin", Interval.Sin, math.Sin, Interval{-2, -1}, true, false},
+		{"cos", Interval.Cos, math.Cos, Interval{-0.5, 0.5}, false, true},
+		{"cos", Interval.Cos, math.Cos, Interval{3, 3.5}, true, false},
+		{"cos", Interval.Cos, math.Cos, Interval{0.5, 1}, false, false},
+		{"cos", Interval.Cos, math.Cos, Interval{6, 7}, false, true},
+	}
+
+	for _, tt := range tests {
+		y := tt.f(tt.x)
+		if y.Lo < -1 || y.Hi > 1 {
+			t.Errorf("%s(%v) = %v goes out of [-1, 1]", tt.name, tt.x, y)
+		}
+		if (y.Lo == -1) != tt.min || (y.Hi == 1) != tt.max {
+			t.Errorf("%s(%v) = %v, want min %v and max %v", tt.name, tt.x, y, tt.min, tt.max)
+		}
+
+		for i := 0; i <= 1000; i++ {
+			x := tt.x.Lo + tt.x.Width()*float64(i)/1000
+			if v := tt.g(x); y.Excludes(v) {
+				t.Errorf("%s(%v) = %v does not hold %s(%g) = %g", tt.name, tt.x, y, tt.name, x, v)
+				break
+			}
+		}
+	}
+}
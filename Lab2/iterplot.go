@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	imgdraw "image/draw"
+	"image/gif"
+	"math"
+	"os"
+	"strconv"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgimg"
+)
+
+// Visual is the way the steps of a method are drawn over the plot of f
+type Visual int64
+
+const (
+	noVisual Visual = iota
+	// points marks the new approximation of every step on the X axis
+	points
+	// chords joins (a, f(a)) and (b, f(b)) of the bracket
+	chords
+	// secants goes through the two last approximations
+	secants
+	// tangents goes from (x_i, f(x_i)) down to the X axis
+	tangents
+	// cobweb walks between y = phi(x) and y = x
+	cobweb
+)
+
+const (
+	gifFrames = 50
+	gifDelay  = 80
+)
+
+var iterationColor = color.RGBA{R: 255, G: 128, A: 255}
+
+// iterationsPlot draws f around the steps of the root with the first k steps
+// on top. Simple iteration is drawn as y = phi(x) and y = x instead of f.
+func iterationsPlot(e Equation, r Result, k int) (*plot.Plot, error) {
+	xMin, xMax, yMin, yMax := iterationsWindow(e, r)
+
+	p := plot.New()
+	p.X.Label.Text = "X"
+	p.Y.Label.Text = "Y"
+
+	if r.Visual == cobweb {
+		p.Title.Text = "x = phi(x) for " + e.s
+
+		phi := plotter.NewFunction(r.Phi)
+		phi.Color = color.RGBA{B: 255, A: 255}
+		phi.Samples = 200
+
+		identity := plotter.NewFunction(func(x float64) float64 { return x })
+		identity.Color = color.RGBA{G: 255, A: 255}
+
+		p.Add(phi, identity)
+		p.Legend.Add("phi(x)", phi)
+		p.Legend.Add("y = x", identity)
+	} else {
+		p.Title.Text = e.s
+
+		f := plotter.NewFunction(e.f)
+		f.Color = color.RGBA{B: 255, A: 255}
+		f.Samples = 200
+
+		xAxis := plotter.NewFunction(func(x float64) float64 { return 0 })
+		xAxis.Dashes = []vg.Length{vg.Points(2), vg.Points(2)}
+		xAxis.Color = color.RGBA{A: 255}
+
+		p.Add(f, xAxis)
+		p.Legend.Add("function", f)
+	}
+
+	if err := addIterations(p, e, r, k); err != nil {
+		return nil, err
+	}
+	p.Legend.ThumbnailWidth = 1 * vg.Inch
+	p.Legend.Top = true
+
+	p.X.Min = xMin
+	p.X.Max = xMax
+	p.Y.Min = yMin
+	p.Y.Max = yMax
+	p.X.Tick.Marker = plot.TickerFunc(preciseTicks)
+	p.Y.Tick.Marker = plot.TickerFunc(preciseTicks)
+
+	return p, nil
+}
+
+// preciseTicks labels the default ticks with enough digits to tell them
+// apart, the window around a root can be much narrower than its position
+func preciseTicks(min, max float64) []plot.Tick {
+	ticks := plot.DefaultTicks{}.Ticks(min, max)
+
+	digits := 3
+	if scale := math.Max(math.Abs(min), math.Abs(max)); max > min && scale > 0 {
+		digits += int(math.Ceil(math.Log10(scale / (max - min))))
+	}
+	if digits < 3 {
+		digits = 3
+	}
+
+	for i := range ticks {
+		if ticks[i].Label != "" {
+			ticks[i].Label = strconv.FormatFloat(ticks[i].Value, 'g', digits, 64)
+		}
+	}
+	return ticks
+}
+
+// addIterations draws the first k steps of r, every step numbered where its
+// new approximation lands
+func addIterations(p *plot.Plot, e Equation, r Result, k int) error {
+	if r.Visual == noVisual || k == 0 {
+		return nil
+	}
+	if k > len(r.Steps) {
+		k = len(r.Steps)
+	}
+
+	var segments []plotter.XYs
+	var marks plotter.XYs
+	var labels []string
+
+	for i, step := range r.Steps[:k] {
+		var mark plotter.XY
+
+		switch r.Visual {
+		case points:
+			mark = plotter.XY{X: step[2]}
+		case chords:
+			// the ends are the values the method used, Illinois halves them
+			segments = append(segments,
+				plotter.XYs{{X: step[0], Y: step[3]}, {X: step[1], Y: step[4]}},
+				plotter.XYs{{X: step[2]}, {X: step[2], Y: step[5]}})
+			mark = plotter.XY{X: step[2]}
+		case secants:
+			segments = append(segments,
+				plotter.XYs{{X: step[0], Y: e.f(step[0])}, {X: step[1], Y: e.f(step[1])}, {X: step[2]}},
+				plotter.XYs{{X: step[2]}, {X: step[2], Y: step[3]}})
+			mark = plotter.XY{X: step[2]}
+		case tangents:
+			// a damped step stops short of the zero of the tangent
+			segments = append(segments,
+				plotter.XYs{{X: step[0], Y: step[1]}, {X: step[0] - step[1]/step[2]}},
+				plotter.XYs{{X: step[3]}, {X: step[3], Y: e.f(step[3])}})
+			mark = plotter.XY{X: step[3]}
+		case cobweb:
+			segments = append(segments,
+				plotter.XYs{{X: step[0], Y: step[0]}, {X: step[0], Y: step[1]}, {X: step[1], Y: step[1]}})
+			mark = plotter.XY{X: step[1], Y: step[1]}
+		}
+
+		marks = append(marks, mark)
+		labels = append(labels, fmt.Sprint(i+1))
+	}
+
+	for i, s := range segments {
+		line, err := plotter.NewLine(s)
+		if err != nil {
+			return err
+		}
+		line.Color = iterationColor
+		p.Add(line)
+		if i == 0 {
+			p.Legend.Add("iterations", line)
+		}
+	}
+
+	scatter, err := plotter.NewScatter(marks)
+	if err != nil {
+		return err
+	}
+	scatter.Shape = draw.CircleGlyph{}
+	scatter.Color = iterationColor
+	p.Add(scatter)
+	if len(segments) == 0 {
+		p.Legend.Add("iterations", scatter)
+	}
+
+	numbers, err := plotter.NewLabels(plotter.XYLabels{XYs: marks, Labels: labels})
+	if err != nil {
+		return err
+	}
+	p.Add(numbers)
+	return nil
+}
+
+// iterationsWindow returns a window around everything the steps touch with
+// some margin. The cobweb window is square, both axes hold values of x.
+func iterationsWindow(e Equation, r Result) (float64, float64, float64, float64) {
+	xs := []float64{r.X}
+	for _, step := range r.Steps {
+		switch r.Visual {
+		case points, chords, secants:
+			xs = append(xs, step[0], step[1], step[2])
+		case tangents:
+			xs = append(xs, step[0], step[3], step[0]-step[1]/step[2])
+		case cobweb:
+			xs = append(xs, step[0], step[1])
+		}
+	}
+
+	xMin, xMax := widen(bounds(xs))
+	if r.Visual == cobweb {
+		return xMin, xMax, xMin, xMax
+	}
+
+	ys := []float64{0}
+	for i := 0; i <= 100; i++ {
+		ys = append(ys, e.f(xMin+(xMax-xMin)*float64(i)/100))
+	}
+	yMin, yMax := widen(bounds(ys))
+
+	return xMin, xMax, yMin, yMax
+}
+
+func bounds(values []float64) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	return lo, hi
+}
+
+func widen(lo, hi float64) (float64, float64) {
+	margin := (hi - lo) / 10
+	if margin == 0 {
+		margin = 1e-3 * math.Max(1, math.Abs(lo))
+	}
+	return lo - margin, hi + margin
+}
+
+// drawIterations saves the steps of every root as <prefix>root_<i>.png, and
+// as an animated <prefix>root_<i>.gif with one frame per step if asked to
+func drawIterations(e Equation, roots []Result, animate bool, prefix string) ([]string, error) {
+	var files []string
+	for i, r := range roots {
+		if r.Visual == noVisual {
+			continue
+		}
+
+		name := fmt.Sprintf("%sroot_%d", prefix, i+1)
+		p, err := iterationsPlot(e, r, len(r.Steps))
+		if err != nil {
+			return files, err
+		}
+		if err := p.Save(7*vg.Inch, 7*vg.Inch, name+".png"); err != nil {
+			return files, err
+		}
+		files = append(files, name+".png")
+
+		if animate {
+			if err := saveGIF(e, r, name+".gif"); err != nil {
+				return files, err
+			}
+			files = append(files, name+".gif")
+		}
+	}
+
+	return files, nil
+}
+
+// saveGIF renders the plot with no steps, then with one more step per frame.
+// Only the first gifFrames steps are shown.
+func saveGIF(e Equation, r Result, filename string) error {
+	n := len(r.Steps)
+	if n > gifFrames {
+		n = gifFrames
+	}
+
+	anim := &gif.GIF{}
+	for k := 0; k <= n; k++ {
+		c := vgimg.New(5*vg.Inch, 5*vg.Inch)
+		p, err := iterationsPlot(e, r, k)
+		if err != nil {
+			return err
+		}
+		p.Draw(draw.New(c))
+
+		img := c.Image()
+		frame := image.NewPaletted(img.Bounds(), palette.Plan9)
+		imgdraw.FloydSteinberg.Draw(frame, img.Bounds(), img, image.Point{})
+
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, gifDelay)
+	}
+	// stay on the last frame a bit longer
+	anim.Delay[n] = 4 * gifDelay
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return gif.EncodeAll(file, anim)
+}
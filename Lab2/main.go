@@ -1,27 +1,42 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/urfave/cli/v2"
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
 	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
 	"gopkg.in/yaml.v2"
 	"image/color"
 	"io/ioutil"
 	"log"
 	"math"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
 )
 
 type data struct {
-	EquationOrSystem int     `yaml:"equationOrSystem"`
-	Method           int     `yaml:"method"`
-	A                float64 `yaml:"a"`
-	B                float64 `yaml:"b"`
-	Eps              float64 `yaml:"eps"`
-	X0               float64 `yaml:"x0"`
-	Y0               float64 `yaml:"y0"`
+	EquationOrSystem int          `yaml:"equationOrSystem"`
+	Method           int          `yaml:"method"`
+	A                float64      `yaml:"a"`
+	B                float64      `yaml:"b"`
+	Eps              float64      `yaml:"eps"`
+	X0               float64      `yaml:"x0"`
+	Y0               float64      `yaml:"y0"`
+	Coefficients     []float64    `yaml:"coefficients"`
+	Expressions      []string     `yaml:"expressions"`
+	InitialGuess     []float64    `yaml:"initialGuess"`
+	Phi              []string     `yaml:"phi"`
+	Box              [][2]float64 `yaml:"box"`
+	LinearSolver     string       `yaml:"linearSolver"`
+	Aitken           bool         `yaml:"aitken"`
+	Digits           int          `yaml:"digits"`
+	Name             string       `yaml:"name"`
+	Tasks            []data       `yaml:"tasks"`
 }
 
 type Equation struct {
@@ -29,24 +44,124 @@ type Equation struct {
 	f           func(x float64) float64
 	derivative  func(x float64) float64
 	derivative2 func(x float64) float64
+	polynomial  Polynomial
 }
 
+// Method with multipleRoots set converges to a root where f does not change
+// sign, otherwise such a root is searched for as an extremum of f
 type Method struct {
-	name string
-	f    func(e Equation, a float64, b float64, eps float64)
+	name          string
+	f             func(e Equation, a float64, b float64, eps float64) (Result, error)
+	multipleRoots bool
 }
 
+// Result of a root finder. Steps is its iteration table, one row per step
+// with the columns named in Header, and Visual tells how to draw the steps.
+// Phi is the iterated function of simple iteration.
+type Result struct {
+	X            float64
+	F            float64
+	Iterations   int
+	Header       []string
+	Steps        [][]float64
+	Q            float64
+	Visual       Visual
+	Phi          func(x float64) float64
+	Multiplicity int
+}
+
+type Bracket struct {
+	a, b     float64
+	touching bool
+}
+
+// System is F(x) = 0 with n equations and n unknowns. Without an analytic
+// jacobian it is approximated by finite differences. phi is the same system
+// written as x = phi(x), it is only needed for simple iteration.
 type System struct {
-	s     []string
-	f     []func(x, y float64) float64
-	jacob func(x, y float64) [][]float64
+	s        []string
+	f        []func(x []float64) float64
+	jacobian func(x []float64) [][]float64
+	phi      []func(x []float64) float64
+}
+
+// SystemMethod with contraction set iterates x = phi(x) and needs
+// max||phi'(x)|| < 1 to be checked over a box before it starts
+type SystemMethod struct {
+	name        string
+	f           func(s System, x0 []float64, eps float64, ls LinearSolver) (SystemResult, error)
+	contraction bool
+}
+
+type SystemResult struct {
+	X           []float64
+	Iterations  int
+	Evaluations int
+	Error       []float64
+	Path        [][]float64
+	// continuation can find several solutions, and the points (x, t) where
+	// its path turned back
+	Solutions     [][]float64
+	TurningPoints [][]float64
+}
+
+// menuItem is a method in the menu, index points into the methods or, for a
+// system, into the system methods
+type menuItem struct {
+	system bool
+	index  int
 }
 
 func main() {
 	methods := []Method{
-		{"Choord method", ChordMethod},
-		{"Newton's method", NewtonMethod},
-		{"Simple iteration method", SimpleIterationMethod},
+		{"Choord method", ChordMethod, false},
+		{"Newton's method", NewtonMethod, true},
+		{"Simple iteration method", SimpleIterationMethod, false},
+		{"Bisection method", BisectionMethod, false},
+		{"Secant method", SecantMethod, false},
+		{"Illinois method", IllinoisMethod, false},
+		{"Brent's method", BrentMethod, false},
+		{"Steffensen's method", SteffensenMethod, false},
+	}
+	simpleIteration, steffensen := methods[2], methods[7]
+	aitken := Method{"Simple iteration method with Aitken", AitkenMethod, false}
+
+	bigMethods := map[string]BigMethod{
+		methods[1].name: {methods[1].name, BigNewtonMethod, true},
+		methods[3].name: {methods[3].name, BigBisectionMethod, false},
+	}
+
+	systemMethods := []SystemMethod{
+		{"Newton's method (system)", NewtonMethodSystem, false},
+		{"Broyden's good method (system)", BroydenGoodMethod, false},
+		{"Broyden's bad method (system)", BroydenBadMethod, false},
+		{"Simple iteration method (system)", SimpleIterationMethodSystem, true},
+		{"Homotopy continuation (system)", HomotopyMethod, false},
+	}
+
+	// menu numbers the methods in the order they were added, new ones go to
+	// the end so that the numbers in existing task files keep their meaning
+	menu := []menuItem{
+		{false, 0}, {false, 1}, {false, 2},
+		{true, 0},
+		{false, 3}, {false, 4}, {false, 5}, {false, 6},
+		{true, 1}, {true, 2}, {true, 3},
+		{false, 7},
+		{true, 4},
+	}
+	methodName := func(item menuItem) string {
+		if item.system {
+			return systemMethods[item.index].name
+		}
+		return methods[item.index].name
+	}
+
+	// a task without a method is solved by Brent's method
+	defaultMethod := 0
+	for i, item := range menu {
+		if !item.system && methodName(item) == "Brent's method" {
+			defaultMethod = i + 1
+		}
 	}
 
 	equations := []Equation{
@@ -55,139 +170,548 @@ func main() {
 			func(x float64) float64 { return math.Sin(x) },
 			func(x float64) float64 { return math.Cos(x) },
 			func(x float64) float64 { return -math.Sin(x) },
+			nil,
 		},
 		{
 			"x^3 - x + 4",
 			func(x float64) float64 { return x*x*x - x + 4 },
 			func(x float64) float64 { return 3*x*x - 1 },
 			func(x float64) float64 { return 6 * x },
+			Polynomial{1, 0, -1, 4},
 		},
 		{
 			"x^3 - 2x^2 + 4x - 8",
 			func(x float64) float64 { return x*x*x - 2*x*x + 4*x - 8 },
 			func(x float64) float64 { return 3*x*x - 4*x + 4 },
 			func(x float64) float64 { return 6*x - 4 },
+			Polynomial{1, -2, 4, -8},
+		},
+		{
+			"x^3 - 3x + 2",
+			func(x float64) float64 { return x*x*x - 3*x + 2 },
+			func(x float64) float64 { return 3*x*x - 3 },
+			func(x float64) float64 { return 6 * x },
+			Polynomial{1, 0, -3, 2},
 		},
 	}
 
 	systems := []System{
-		{[]string{"x^2 + y^2 = 4", "y = 3x^2"}, []func(x, y float64) float64{
-			func(x, y float64) float64 { return x*x + y*y - 4 },
-			func(x, y float64) float64 { return y - 3*x*x },
-		}, func(x, y float64) [][]float64 {
+		{[]string{"x^2 + y^2 = 4", "y = 3x^2"}, []func(x []float64) float64{
+			func(x []float64) float64 { return x[0]*x[0] + x[1]*x[1] - 4 },
+			func(x []float64) float64 { return x[1] - 3*x[0]*x[0] },
+		}, func(x []float64) [][]float64 {
 			return [][]float64{
-				{2 * x, 2 * y, 4 - x*x - y*y},
-				{-6 * x, 1, 3*x*x - y},
+				{2 * x[0], 2 * x[1]},
+				{-6 * x[0], 1},
 			}
+		}, []func(x []float64) float64{
+			func(x []float64) float64 { return math.Sqrt(x[1] / 3) },
+			func(x []float64) float64 { return math.Sqrt(4 - x[0]*x[0]) },
 		}},
-		{[]string{"y = x^2 - 1", "y = 1"}, []func(x, y float64) float64{
-			func(x, y float64) float64 { return x*x - y*y - 1 },
-			func(x, y float64) float64 { return y - 1 },
-		}, func(x, y float64) [][]float64 {
+		{[]string{"y = x^2 - 1", "y = 1"}, []func(x []float64) float64{
+			func(x []float64) float64 { return x[1] - x[0]*x[0] + 1 },
+			func(x []float64) float64 { return x[1] - 1 },
+		}, func(x []float64) [][]float64 {
 			return [][]float64{
-				{2 * x, 1, -x*x + 1 + y},
-				{0, 1, 1 - y},
+				{-2 * x[0], 1},
+				{0, 1},
 			}
+		}, []func(x []float64) float64{
+			func(x []float64) float64 { return math.Sqrt(x[1] + 1) },
+			func(x []float64) float64 { return 1 },
 		}},
 	}
 
+	flags := []cli.Flag{
+		&cli.BoolFlag{
+			Name:    "console-input",
+			Aliases: []string{"i"},
+			Usage:   "Use console input",
+		},
+		&cli.StringFlag{
+			Name:    "filename",
+			Aliases: []string{"f"},
+			Usage:   "Filename if not console input (default: data.yml)",
+		},
+	}
+
+	// runTask solves one task and returns its roots in short for the batch
+	// summary. prefix goes before the names of the saved plots.
+	runTask := func(cCtx *cli.Context, d data, prefix string) (string, error) {
+		if d.Method == 0 {
+			d.Method = defaultMethod
+		}
+
+		if d.Method < 1 || d.Method > len(menu) {
+			return "", errors.New("invalid method")
+		}
+		item := menu[d.Method-1]
+
+		if !item.system {
+			if d.EquationOrSystem < 1 || d.EquationOrSystem > len(equations) {
+				return "", errors.New("invalid equation")
+			}
+
+			e := equations[d.EquationOrSystem-1]
+			brackets := isolateRoots(e, d.A, d.B)
+			if len(brackets) == 0 {
+				return "", errors.New("no roots in this interval")
+			}
+
+			m := methods[item.index]
+			if d.Aitken || cCtx.Bool("aitken") {
+				if m.name != simpleIteration.name {
+					return "", errors.New("Aitken extrapolation works only with simple iteration")
+				}
+				m = aitken
+			}
+
+			if cCtx.IsSet("digits") {
+				d.Digits = cCtx.Int("digits")
+			}
+			if d.Digits > 0 {
+				bm, ok := bigMethods[m.name]
+				if !ok {
+					return "", errors.New("digits work only with Newton's method and the bisection method")
+				}
+
+				f, err := ParseExpr(e.s)
+				if err != nil {
+					return "", err
+				}
+
+				roots, notes, err := findBigRoots(f, bm, brackets, d.Digits)
+				if err != nil {
+					return "", err
+				}
+				if err := printBigRoots(roots, d.Digits, cCtx.String("format")); err != nil {
+					return "", err
+				}
+				for _, note := range notes {
+					fmt.Println(note)
+				}
+				return formatBigRoots(roots, d.Digits), nil
+			}
+
+			if d.Eps <= 0 {
+				return "", errors.New("need eps > 0")
+			}
+			roots, notes, err := findRoots(e, m, brackets, d.Eps)
+			if err != nil {
+				return "", err
+			}
+			if err := printRoots(roots, cCtx.String("format"), cCtx.Bool("steps")); err != nil {
+				return "", err
+			}
+			for _, note := range notes {
+				fmt.Println(note)
+			}
+
+			// accelerated methods are shown next to the plain iterations
+			if m.name == aitken.name || m.name == steffensen.name {
+				t := CompareIterations(e, []Method{simpleIteration, m}, brackets, d.A, d.B, d.Eps)
+				fmt.Println()
+				if err := t.Write(os.Stdout, cCtx.String("format")); err != nil {
+					return "", err
+				}
+			}
+			if err := drawPlot(e, d.A, d.B, roots, prefix+"function.png"); err != nil {
+				return formatRoots(roots), err
+			}
+			fmt.Printf("Plot saved to %sfunction.png\n", prefix)
+			files, err := drawIterations(e, roots, cCtx.Bool("gif"), prefix)
+			for _, name := range files {
+				fmt.Println("Iterations saved to", name)
+			}
+			return formatRoots(roots), err
+		}
+
+		s, x0, err := chooseSystem(d, systems)
+		if err != nil {
+			return "", err
+		}
+
+		if d.Eps <= 0 {
+			return "", errors.New("need eps > 0")
+		}
+
+		m := systemMethods[item.index]
+		if m.contraction {
+			if len(d.Box) != len(x0) {
+				return "", fmt.Errorf("box must have bounds for all %d unknowns", len(x0))
+			}
+
+			q, err := contractionFactor(s, d.Box)
+			if err != nil {
+				return "", err
+			}
+			fmt.Println("max||phi'(x)|| =", q)
+			if q >= 1 {
+				return "", errors.New("phi is not a contraction in this box, the method may diverge")
+			}
+		}
+
+		ls, err := chooseLinearSolver(d)
+		if err != nil {
+			return "", err
+		}
+
+		r, err := m.f(s, x0, d.Eps, ls)
+		if err != nil {
+			return "", err
+		}
+		printSystemResult(s, r)
+
+		// Broyden's methods are there to save evaluations of F, so only they
+		// are compared with Newton's method
+		broyden := m.name == systemMethods[1].name || m.name == systemMethods[2].name
+		if !broyden {
+			fmt.Println("Function evaluations:", r.Evaluations)
+		} else if newton, err := NewtonMethodSystem(s, x0, d.Eps, ls); err != nil {
+			fmt.Printf("Function evaluations: %d (%s failed: %v)\n", r.Evaluations, systemMethods[0].name, err)
+		} else {
+			fmt.Printf("Function evaluations: %d (%s: %d, saved %d)\n",
+				r.Evaluations, systemMethods[0].name, newton.Evaluations, newton.Evaluations-r.Evaluations)
+		}
+
+		if len(x0) == 2 {
+			if err := drawSystem(s, x0, r, prefix+"system.png"); err != nil {
+				return formatPoint(r.X), err
+			}
+			fmt.Printf("Plot saved to %ssystem.png\n", prefix)
+		}
+		return formatPoint(r.X), nil
+	}
+
+	// describeTask names the method and the equation or system of a task for
+	// the batch summary, even when they are out of range
+	describeTask := func(d data) (string, string) {
+		method := fmt.Sprint(d.Method)
+		if d.Method == 0 {
+			d.Method = defaultMethod
+		}
+		isSystem := true
+		if d.Method >= 1 && d.Method <= len(menu) {
+			method = methodName(menu[d.Method-1])
+			isSystem = menu[d.Method-1].system
+		}
+
+		switch {
+		case !isSystem && d.EquationOrSystem >= 1 && d.EquationOrSystem <= len(equations):
+			return method, equations[d.EquationOrSystem-1].s
+		case !isSystem:
+			return method, fmt.Sprintf("equation %d", d.EquationOrSystem)
+		case len(d.Expressions) > 0:
+			return method, strings.Join(d.Expressions, ", ")
+		case d.EquationOrSystem >= 1 && d.EquationOrSystem <= len(systems):
+			return method, strings.Join(systems[d.EquationOrSystem-1].s, ", ")
+		}
+		return method, fmt.Sprintf("system %d", d.EquationOrSystem)
+	}
+
 	app := &cli.App{
 		Name:  "Computation",
 		Usage: "Solve equations",
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
+			&cli.BoolFlag{
+				Name:  "steps",
+				Usage: "Print the iteration table of every root",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Table format: text, markdown or csv",
+				Value: "text",
+			},
+			&cli.BoolFlag{
+				Name:  "gif",
+				Usage: "Also save the iterations of every root as an animated GIF",
+			},
 			&cli.BoolFlag{
-				Name:    "console-input",
-				Aliases: []string{"i"},
-				Usage:   "Use console input",
+				Name:  "aitken",
+				Usage: "Extrapolate simple iteration with Aitken's delta-squared process",
+			},
+			&cli.IntFlag{
+				Name:  "digits",
+				Usage: "Find the roots to this many digits with big.Float (Newton's and bisection methods)",
 			},
 			&cli.StringFlag{
-				Name:    "filename",
-				Aliases: []string{"f"},
-				Usage:   "Filename if not console input (default: data.yml)",
+				Name:    "report",
+				Aliases: []string{"r"},
+				Usage:   "Also write the summary of a batch of tasks to this file",
+			},
+		}, flags...),
+		Commands: []*cli.Command{
+			{
+				Name:  "compare",
+				Usage: "Run every method on the equation and compare them",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Table format: text, markdown or csv",
+						Value: "text",
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Also write the table to this file",
+					},
+				}, flags...),
+				Action: func(cCtx *cli.Context) error {
+					var d data
+
+					if cCtx.Bool("console-input") {
+						if err := scanEquation(&d, equations); err != nil {
+							return err
+						}
+					} else {
+						var err error
+						d, err = readData(cCtx.String("filename"))
+						if err != nil {
+							return err
+						}
+					}
+
+					if d.EquationOrSystem < 1 || d.EquationOrSystem > len(equations) {
+						return errors.New("invalid equation")
+					}
+					if d.Eps <= 0 {
+						return errors.New("need eps > 0")
+					}
+
+					t, err := Compare(equations[d.EquationOrSystem-1], methods, d.A, d.B, d.Eps)
+					if err != nil {
+						return err
+					}
+
+					if err := t.Write(os.Stdout, cCtx.String("format")); err != nil {
+						return err
+					}
+
+					if len(cCtx.String("output")) > 0 {
+						file, err := os.Create(cCtx.String("output"))
+						if err != nil {
+							return err
+						}
+						defer file.Close()
+
+						if err := t.Write(file, cCtx.String("format")); err != nil {
+							return err
+						}
+						fmt.Println("Table saved to", cCtx.String("output"))
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:  "polynomial",
+				Usage: "Find all complex roots of a polynomial",
+				Flags: flags,
+				Action: func(cCtx *cli.Context) error {
+					p, err := readPolynomial(cCtx, equations)
+					if err != nil {
+						return err
+					}
+
+					roots, err := PolynomialRoots(p)
+					if err != nil {
+						return err
+					}
+					printPolynomialRoots(roots)
+
+					return nil
+				},
+			},
+			{
+				Name:  "fractal",
+				Usage: "Draw the basins of attraction of Newton's method for a polynomial",
+				Flags: append([]cli.Flag{
+					&cli.IntFlag{
+						Name:  "width",
+						Usage: "Image width in pixels",
+						Value: 800,
+					},
+					&cli.IntFlag{
+						Name:  "height",
+						Usage: "Image height in pixels",
+						Value: 800,
+					},
+					&cli.Float64Flag{
+						Name:  "radius",
+						Usage: "Half side of the drawn square around 0 (default: fits all roots)",
+					},
+					&cli.IntFlag{
+						Name:  "workers",
+						Usage: "Number of goroutines",
+						Value: runtime.NumCPU(),
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "PNG file",
+						Value:   "fractal.png",
+					},
+				}, flags...),
+				Action: func(cCtx *cli.Context) error {
+					p, err := readPolynomial(cCtx, equations)
+					if err != nil {
+						return err
+					}
+
+					if cCtx.Int("width") < 1 || cCtx.Int("height") < 1 || cCtx.Int("workers") < 1 {
+						return errors.New("width, height and workers must be positive")
+					}
+
+					roots, err := PolynomialRoots(p)
+					if err != nil {
+						return err
+					}
+					printPolynomialRoots(roots)
+
+					radius := cCtx.Float64("radius")
+					if radius <= 0 {
+						radius = fractalRadius(roots)
+					}
+
+					img := NewtonFractal(p, roots, cCtx.Int("width"), cCtx.Int("height"), radius, cCtx.Int("workers"))
+					if err := saveFractal(img, cCtx.String("output")); err != nil {
+						return err
+					}
+					fmt.Println("Fractal saved to", cCtx.String("output"))
+
+					return nil
+				},
+			},
+			{
+				Name:  "verify",
+				Usage: "Prove where the roots are with interval arithmetic",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Table format: text, markdown or csv",
+						Value: "text",
+					},
+				}, flags...),
+				Action: func(cCtx *cli.Context) error {
+					var d data
+
+					if cCtx.Bool("console-input") {
+						if err := scanEquation(&d, equations); err != nil {
+							return err
+						}
+					} else {
+						var err error
+						d, err = readData(cCtx.String("filename"))
+						if err != nil {
+							return err
+						}
+					}
+
+					if d.EquationOrSystem < 1 || d.EquationOrSystem > len(equations) {
+						return errors.New("invalid equation")
+					}
+					if d.A >= d.B || d.Eps <= 0 {
+						return errors.New("need a < b and eps > 0")
+					}
+
+					f, err := ParseExpr(equations[d.EquationOrSystem-1].s)
+					if err != nil {
+						return err
+					}
+
+					enclosures, err := VerifyRoots(f, f.Derivative(0), d.A, d.B, d.Eps)
+					if err != nil {
+						return err
+					}
+
+					return printEnclosures(enclosures, d.A, d.B, cCtx.String("format"))
+				},
 			},
 		},
 		Action: func(cCtx *cli.Context) error {
 			var d data
 
 			if cCtx.Bool("console-input") {
-				isSystem := false
-
-				for i, method := range methods {
-					fmt.Printf("%d. %s\n", i+1, method.name)
+				for i, item := range menu {
+					fmt.Printf("%d. %s\n", i+1, methodName(item))
 				}
-				fmt.Println("4. Newton's method (system)")
 				fmt.Print("Choose method: ")
 				fmt.Scan(&d.Method)
-				if d.Method == len(methods)+1 {
-					isSystem = true
-				}
-				if !isSystem && (d.Method < 1 || d.Method > len(methods)) {
+				if d.Method < 1 || d.Method > len(menu) {
 					return fmt.Errorf("invalid method")
 				}
+				item := menu[d.Method-1]
+				isSystem := item.system
 
 				if isSystem {
 					for i, system := range systems {
 						fmt.Printf("%d. %s\n", i+1, system.s)
 					}
+					fmt.Printf("%d. Enter your own system\n", len(systems)+1)
 					fmt.Print("Choose system: ")
 					fmt.Scan(&d.EquationOrSystem)
-					if d.EquationOrSystem < 1 || d.EquationOrSystem > len(systems) {
+					if d.EquationOrSystem < 1 || d.EquationOrSystem > len(systems)+1 {
 						return fmt.Errorf("invalid system")
 					}
 
-					fmt.Print("Enter x0: ")
-					fmt.Scan(&d.X0)
-					fmt.Print("Enter y0: ")
-					fmt.Scan(&d.Y0)
-					fmt.Print("Enter eps: ")
-					fmt.Scan(&d.Eps)
-				} else {
-					for i, equation := range equations {
-						fmt.Printf("%d. %s\n", i+1, equation.s)
+					n := len(systems[0].f)
+					if d.EquationOrSystem == len(systems)+1 {
+						fmt.Print("Enter number of equations: ")
+						fmt.Scan(&n)
+						if n < 1 {
+							return fmt.Errorf("invalid number of equations")
+						}
+
+						fmt.Println("Enter equations in x1..xn (or x, y, z), one per line:")
+						d.Expressions = make([]string, n)
+						for i := range d.Expressions {
+							d.Expressions[i] = readLine()
+						}
+
+						if systemMethods[item.index].contraction {
+							fmt.Println("Enter phi1..phin of x = phi(x), one per line:")
+							d.Phi = make([]string, n)
+							for i := range d.Phi {
+								d.Phi[i] = readLine()
+							}
+						}
+					} else {
+						n = len(systems[d.EquationOrSystem-1].f)
 					}
-					fmt.Print("Choose equation: ")
-					fmt.Scan(&d.EquationOrSystem)
-					if d.EquationOrSystem < 1 || d.EquationOrSystem > len(equations) {
-						return fmt.Errorf("invalid equation")
+
+					if systemMethods[item.index].contraction {
+						d.Box = make([][2]float64, n)
+						for i := range d.Box {
+							fmt.Printf("Enter bounds of x%d for the contraction check (a b): ", i+1)
+							fmt.Scan(&d.Box[i][0], &d.Box[i][1])
+						}
 					}
 
-					fmt.Print("Enter a: ")
-					fmt.Scan(&d.A)
-					fmt.Print("Enter b: ")
-					fmt.Scan(&d.B)
+					fmt.Printf("Enter initial guess (%d numbers): ", n)
+					d.InitialGuess = make([]float64, n)
+					for i := range d.InitialGuess {
+						fmt.Scan(&d.InitialGuess[i])
+					}
 					fmt.Print("Enter eps: ")
 					fmt.Scan(&d.Eps)
+				} else {
+					if err := scanEquation(&d, equations); err != nil {
+						return err
+					}
 				}
 			} else {
-				filename := "data.yml"
-
-				if len(cCtx.String("filename")) > 0 {
-					filename = cCtx.String("filename")
-				}
-
-				yamlFile, err := ioutil.ReadFile(filename)
-				if err != nil {
-					return err
-				}
-
-				err = yaml.Unmarshal(yamlFile, &d)
+				var err error
+				d, err = readData(cCtx.String("filename"))
 				if err != nil {
 					return err
 				}
 			}
 
-			if d.Method >= 1 && d.Method <= len(methods) {
-				checkRoots(equations[d.EquationOrSystem-1], d.A, d.B)
-				methods[d.Method-1].f(equations[d.EquationOrSystem-1], d.A, d.B, d.Eps)
-				drawPlot(equations[d.EquationOrSystem-1], d.A, d.B)
-				fmt.Println("Plot saved to function.png")
-			} else {
-				NewtonMethodSystem(systems[d.EquationOrSystem-1], d.X0, d.Y0, d.Eps)
-				drawSystem(systems[d.EquationOrSystem-1], d.X0, d.Y0)
-				fmt.Println("Plot saved to system.png")
+			if len(d.Tasks) > 0 {
+				return runBatch(cCtx, d.Tasks, runTask, describeTask)
 			}
-
-			return nil
+			_, err := runTask(cCtx, d, "")
+			return err
 		},
 	}
 
@@ -196,60 +720,471 @@ func main() {
 	}
 }
 
-func checkRoots(e Equation, a, b float64) {
-	if e.f(a)*e.f(b) > 0 {
-		log.Fatal("No roots in this interval")
+func readData(filename string) (data, error) {
+	var d data
+
+	if len(filename) == 0 {
+		filename = "data.yml"
+	}
+
+	yamlFile, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return d, err
+	}
+
+	err = yaml.Unmarshal(yamlFile, &d)
+	return d, err
+}
+
+// readPolynomial takes the coefficients from the console, from the file or
+// from the chosen equation if it is a polynomial
+func readPolynomial(cCtx *cli.Context, equations []Equation) (Polynomial, error) {
+	if cCtx.Bool("console-input") {
+		var n int
+		fmt.Print("Enter degree: ")
+		fmt.Scan(&n)
+		if n < 1 {
+			return nil, fmt.Errorf("invalid degree")
+		}
+
+		fmt.Printf("Enter %d coefficients from the highest power: ", n+1)
+		p := make(Polynomial, n+1)
+		for i := range p {
+			fmt.Scan(&p[i])
+		}
+		return p, nil
+	}
+
+	d, err := readData(cCtx.String("filename"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(d.Coefficients) > 0 {
+		return d.Coefficients, nil
+	}
+	if d.EquationOrSystem < 1 || d.EquationOrSystem > len(equations) || equations[d.EquationOrSystem-1].polynomial == nil {
+		return nil, fmt.Errorf("no coefficients and no polynomial equation chosen")
+	}
+	return equations[d.EquationOrSystem-1].polynomial, nil
+}
+
+func scanEquation(d *data, equations []Equation) error {
+	for i, equation := range equations {
+		fmt.Printf("%d. %s\n", i+1, equation.s)
 	}
+	fmt.Print("Choose equation: ")
+	fmt.Scan(&d.EquationOrSystem)
+	if d.EquationOrSystem < 1 || d.EquationOrSystem > len(equations) {
+		return fmt.Errorf("invalid equation")
+	}
+
+	fmt.Print("Enter a: ")
+	fmt.Scan(&d.A)
+	fmt.Print("Enter b: ")
+	fmt.Scan(&d.B)
+	fmt.Print("Enter eps: ")
+	fmt.Scan(&d.Eps)
 
-	cnt := 0
-	for i := a; i < b; i += 0.01 {
-		if e.f(i)*e.f(i+0.01) < 0 {
-			if cnt > 0 {
-				log.Fatal("More than 1 root in this interval")
+	return nil
+}
+
+// readLine reads a whole line from the console, skipping blank ones. It reads
+// byte by byte so it can be mixed with fmt.Scan.
+func readLine() string {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(b)
+		if n == 0 || err != nil || b[0] == '\n' {
+			if len(strings.TrimSpace(string(line))) > 0 || n == 0 || err != nil {
+				return strings.TrimSpace(string(line))
 			}
-			cnt++
+			line = line[:0]
+			continue
+		}
+		line = append(line, b[0])
+	}
+}
+
+// chooseSystem returns either the typed system or a built-in one, together
+// with the initial guess
+func chooseSystem(d data, systems []System) (System, []float64, error) {
+	var s System
+
+	if len(d.Expressions) > 0 {
+		var err error
+		s, err = parseSystem(d.Expressions, d.Phi)
+		if err != nil {
+			return s, nil, err
+		}
+	} else {
+		if d.EquationOrSystem < 1 || d.EquationOrSystem > len(systems) {
+			return s, nil, errors.New("invalid system")
 		}
+		s = systems[d.EquationOrSystem-1]
 	}
+
+	x0 := d.InitialGuess
+	if len(x0) == 0 {
+		x0 = []float64{d.X0, d.Y0}
+	}
+	if len(x0) != len(s.f) {
+		return s, nil, fmt.Errorf("initial guess must have %d values", len(s.f))
+	}
+
+	return s, x0, nil
+}
+
+func chooseLinearSolver(d data) (LinearSolver, error) {
+	switch d.LinearSolver {
+	case "", "gauss":
+		return GaussSolver{}, nil
+	case "gauss-seidel":
+		return GaussSeidelSolver{Accuracy: d.Eps / 10}, nil
+	}
+	return nil, fmt.Errorf("unknown linear solver %q (gauss or gauss-seidel)", d.LinearSolver)
 }
 
-func drawSystem(s System, x0, y0 float64) {
+func parseSystem(expressions []string, phi []string) (System, error) {
+	s := System{s: expressions}
+
+	var err error
+	s.f, err = parseFunctions(expressions, len(expressions))
+	if err != nil {
+		return s, err
+	}
+
+	if len(phi) > 0 {
+		if len(phi) != len(expressions) {
+			return s, fmt.Errorf("phi must have %d functions", len(expressions))
+		}
+		s.phi, err = parseFunctions(phi, len(expressions))
+	}
+
+	return s, err
+}
+
+func parseFunctions(expressions []string, n int) ([]func(x []float64) float64, error) {
+	var fs []func(x []float64) float64
+
+	for _, str := range expressions {
+		e, err := ParseExpr(str)
+		if err != nil {
+			return nil, err
+		}
+		if e.NumVars() > n {
+			return nil, fmt.Errorf("%q uses more unknowns than there are equations", str)
+		}
+		fs = append(fs, e.Eval)
+	}
+
+	return fs, nil
+}
+
+const isolationStep = 0.01
+
+// isolateRoots splits [a, b] into brackets holding one root each. Besides sign
+// changes it keeps the extrema of f that come towards zero, since a root of
+// even multiplicity only touches the axis.
+func isolateRoots(e Equation, a, b float64) []Bracket {
+	n := int(math.Ceil((b - a) / isolationStep))
+	if n < 1 {
+		n = 1
+	}
+	h := (b - a) / float64(n)
+
+	var brackets []Bracket
+	x0, f0, d0 := a, e.f(a), e.derivative(a)
+	if f0 == 0 {
+		brackets = append(brackets, Bracket{a: a, b: a + h})
+	}
+	for i := 1; i <= n; i++ {
+		x1 := a + float64(i)*h
+		f1, d1 := e.f(x1), e.derivative(x1)
+
+		switch {
+		case f1 == 0 || f0*f1 < 0:
+			brackets = append(brackets, Bracket{a: x0, b: x1})
+		case f0 != 0 && d0*d1 < 0 && f0*d0 < 0:
+			brackets = append(brackets, Bracket{a: x0, b: x1, touching: true})
+		}
+
+		x0, f0, d0 = x1, f1, d1
+	}
+
+	return brackets
+}
+
+// findRoots runs the method on every bracket. A touching root is searched for
+// as a root of f' unless the method handles multiple roots itself, and dropped
+// when f does not actually reach zero there. The dropped candidates and the
+// brackets where the method fails are described in the returned notes, the
+// error is returned only when no bracket gives a root.
+func findRoots(e Equation, m Method, brackets []Bracket, eps float64) ([]Result, []string, error) {
+	var roots []Result
+	var notes []string
+	var failed error
+	for _, br := range brackets {
+		if !br.touching {
+			r, err := m.f(e, br.a, br.b, eps)
+			if err != nil {
+				failed = fmt.Errorf("[%g, %g]: %w", br.a, br.b, err)
+				notes = append(notes, fmt.Sprintf("[%g, %g]: no root found: %v", br.a, br.b, err))
+				continue
+			}
+			roots = append(roots, r)
+			continue
+		}
+
+		g := e
+		if !m.multipleRoots {
+			g = derivativeEquation(e)
+		}
+		r, err := m.f(g, br.a, br.b, eps)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("[%g, %g]: possible touching root discarded: %v", br.a, br.b, err))
+			continue
+		}
+		if !m.multipleRoots {
+			// the steps were made on f', they do not fit the plot of f
+			r.F = e.f(r.X)
+			r.Visual = noVisual
+		}
+		if math.Abs(r.F) > eps {
+			notes = append(notes, fmt.Sprintf("[%g, %g]: possible touching root discarded, f(%g) = %g", br.a, br.b, r.X, r.F))
+			continue
+		}
+		roots = append(roots, r)
+	}
+
+	if len(roots) == 0 && failed != nil {
+		return nil, nil, failed
+	}
+	return roots, notes, nil
+}
+
+func derivativeEquation(e Equation) Equation {
+	h := 1e-5
+	return Equation{
+		"(" + e.s + ")'",
+		e.derivative,
+		e.derivative2,
+		func(x float64) float64 { return (e.derivative2(x+h) - e.derivative2(x-h)) / (2 * h) },
+		e.polynomial.Derivative(),
+	}
+}
+
+func printRoots(roots []Result, format string, steps bool) error {
+	if len(roots) == 0 {
+		fmt.Println("No roots found")
+		return nil
+	}
+
+	// q is only known for simple iteration, multiplicity for Newton's method
+	withQ, withM := false, false
+	for _, r := range roots {
+		withQ = withQ || r.Q > 0
+		withM = withM || r.Multiplicity > 0
+	}
+
+	t := Table{Header: []string{"#", "X", "f(x)", "Iterations"}}
+	if withQ {
+		t.Header = append(t.Header, "q")
+	}
+	if withM {
+		t.Header = append(t.Header, "Multiplicity")
+	}
+	for i, r := range roots {
+		row := []string{fmt.Sprint(i + 1), fmt.Sprintf("%.15g", r.X), fmt.Sprintf("%.6g", r.F), fmt.Sprint(r.Iterations)}
+		if withQ {
+			row = append(row, fmt.Sprintf("%.4g", r.Q))
+		}
+		if withM {
+			row = append(row, fmt.Sprint(r.Multiplicity))
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	if err := t.Write(os.Stdout, format); err != nil {
+		return err
+	}
+
+	if steps {
+		for i, r := range roots {
+			fmt.Printf("\nRoot %d\n", i+1)
+			if err := r.StepTable().Write(os.Stdout, format); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// formatRoots lists the roots in one line
+func formatRoots(roots []Result) string {
+	if len(roots) == 0 {
+		return "no roots"
+	}
+
+	xs := make([]string, len(roots))
+	for i, r := range roots {
+		xs[i] = fmt.Sprintf("%.10g", r.X)
+	}
+	return strings.Join(xs, ", ")
+}
+
+func (r Result) StepTable() Table {
+	t := Table{Header: append([]string{"#"}, r.Header...)}
+	for i, step := range r.Steps {
+		row := []string{fmt.Sprint(i + 1)}
+		for _, item := range step {
+			row = append(row, strconv.FormatFloat(item, 'g', 10, 64))
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
+
+func printSystemResult(s System, r SystemResult) {
+	for i, x := range r.X {
+		fmt.Printf("x%d = %v\n", i+1, x)
+	}
+	for i, f := range s.f {
+		fmt.Printf("f%d(x) = %v\n", i+1, f(r.X))
+	}
+	if r.Error != nil {
+		fmt.Println("Error vector:", r.Error)
+	}
+	fmt.Println("Number of iterations:", r.Iterations)
+
+	if len(r.Solutions) > 1 {
+		fmt.Println("Solutions found on the path:")
+		for i, x := range r.Solutions {
+			fmt.Printf("%d. %s\n", i+1, formatPoint(x))
+		}
+	}
+	if len(r.TurningPoints) > 0 {
+		fmt.Println("Turning points (x, t):")
+		for i, y := range r.TurningPoints {
+			fmt.Printf("%d. %s\n", i+1, formatPoint(y))
+		}
+	}
+}
+
+func printPolynomialRoots(roots []PolynomialRoot) {
+	fmt.Printf("%-4s%-44s%-14s%s\n", "#", "Root", "Multiplicity", "Residual")
+	for i, r := range roots {
+		fmt.Printf("%-4d%-44s%-14d%.3g\n", i+1, formatComplex(r.Z), r.Multiplicity, r.Residual)
+	}
+}
+
+func formatComplex(z complex128) string {
+	if imag(z) == 0 {
+		return fmt.Sprintf("%.15g", real(z))
+	}
+	if imag(z) < 0 {
+		return fmt.Sprintf("%.15g - %.15gi", real(z), -imag(z))
+	}
+	return fmt.Sprintf("%.15g + %.15gi", real(z), imag(z))
+}
+
+const maxPathLabels = 50
+
+// drawSystem draws the zero level sets of both equations and the way the
+// method went from the initial guess to the root
+func drawSystem(s System, x0 []float64, r SystemResult, filename string) error {
 	p := plot.New()
 	p.Title.Text = s.s[0] + " and " + s.s[1]
 	p.X.Label.Text = "X"
 	p.Y.Label.Text = "Y"
 
-	f1 := plotter.NewFunction(func(x float64) float64 {
-		if math.Abs(x) < 2 {
-			return math.Sqrt(4 - x*x)
-		}
-		return 0
-	})
+	// a continuation path may run far away, what matters is where it found
+	// the solutions
+	window := r.Path
+	if len(r.Solutions) > 0 {
+		window = append(append([][]float64{x0}, r.Solutions...), r.TurningPoints...)
+	}
+	xMin, xMax, yMin, yMax := pointsBox(window)
+
+	f1 := NewContour(s.f[0], xMin, xMax, yMin, yMax)
 	f1.Color = color.RGBA{B: 255, A: 255}
 
-	f2 := plotter.NewFunction(func(x float64) float64 { return 3 * x * x })
+	f2 := NewContour(s.f[1], xMin, xMax, yMin, yMax)
 	f2.Color = color.RGBA{G: 255, A: 255}
 
-	xAxis := plotter.NewFunction(func(x float64) float64 { return 0 })
-	xAxis.Dashes = []vg.Length{vg.Points(2), vg.Points(2)}
-	xAxis.Width = vg.Points(1.5)
-	xAxis.Color = color.RGBA{A: 255}
+	p.Add(f1, f2)
+	p.Legend.Add(s.s[0], f1)
+	p.Legend.Add(s.s[1], f2)
 
-	p.Add(f1, xAxis, f2)
-	p.Legend.Add("f1", f1)
-	p.Legend.Add("f2", f2)
-	p.Legend.ThumbnailWidth = 1 * vg.Inch
+	path := make(plotter.XYs, len(r.Path))
+	labels := make([]string, len(r.Path))
+	for i, x := range r.Path {
+		path[i].X, path[i].Y = x[0], x[1]
+		labels[i] = fmt.Sprint(i)
+	}
 
-	p.X.Min = x0 - 5
-	p.X.Max = x0 + 2
-	p.Y.Min = y0 - 5
-	p.Y.Max = y0 + 5
+	line, points, err := plotter.NewLinePoints(path)
+	if err != nil {
+		return err
+	}
+	line.Dashes = []vg.Length{vg.Points(3), vg.Points(2)}
+	points.Shape = draw.CircleGlyph{}
+	points.Color = color.RGBA{R: 255, G: 128, A: 255}
+	p.Add(line, points)
+	p.Legend.Add("iterations", line, points)
 
-	if err := p.Save(7*vg.Inch, 7*vg.Inch, "system.png"); err != nil {
-		log.Fatal(err)
+	// a long continuation path would be covered by its numbers
+	if len(path) <= maxPathLabels {
+		numbers, err := plotter.NewLabels(plotter.XYLabels{XYs: path, Labels: labels})
+		if err != nil {
+			return err
+		}
+		p.Add(numbers)
+	}
+
+	start, err := plotter.NewScatter(plotter.XYs{{X: x0[0], Y: x0[1]}})
+	if err != nil {
+		return err
+	}
+	start.Shape = draw.BoxGlyph{}
+	start.Color = color.RGBA{G: 160, A: 255}
+	start.Radius = vg.Points(4)
+	p.Add(start)
+	p.Legend.Add("initial guess", start)
+
+	solutions := r.Solutions
+	if len(solutions) == 0 {
+		solutions = [][]float64{r.X}
 	}
+	roots := make(plotter.XYs, len(solutions))
+	for i, x := range solutions {
+		roots[i].X, roots[i].Y = x[0], x[1]
+	}
+	root, err := plotter.NewScatter(roots)
+	if err != nil {
+		return err
+	}
+	root.Shape = draw.CrossGlyph{}
+	root.Color = color.RGBA{R: 255, A: 255}
+	root.Radius = vg.Points(6)
+	p.Add(root)
+	p.Legend.Add("root", root)
+	p.Legend.ThumbnailWidth = 1 * vg.Inch
+	p.Legend.Top = true
+
+	p.X.Min = xMin
+	p.X.Max = xMax
+	p.Y.Min = yMin
+	p.Y.Max = yMax
+
+	return p.Save(7*vg.Inch, 7*vg.Inch, filename)
 }
 
-func drawPlot(e Equation, a, b float64) {
+// drawPlot draws f and f' on the whole interval with the steps of every root
+// on top. The roots are usually close together at this scale, drawIterations
+// zooms in on each of them.
+func drawPlot(e Equation, a, b float64, roots []Result, filename string) error {
 	p := plot.New()
 	p.Title.Text = e.s
 	p.X.Label.Text = "X"
@@ -269,6 +1204,46 @@ func drawPlot(e Equation, a, b float64) {
 	p.Add(f, xAxis, der)
 	p.Legend.Add("function", f)
 	p.Legend.Add("derivative", der)
+
+	for i, r := range roots {
+		if r.Visual == cobweb {
+			// phi differs from root to root, it is drawn only around its own steps
+			xMin, xMax, _, _ := iterationsWindow(e, r)
+
+			phi := plotter.NewFunction(r.Phi)
+			phi.XMin, phi.XMax = xMin, xMax
+			phi.Color = color.RGBA{R: 128, B: 255, A: 255}
+
+			identity := plotter.NewFunction(func(x float64) float64 { return x })
+			identity.XMin, identity.XMax = xMin, xMax
+			identity.Color = color.RGBA{G: 128, A: 255}
+
+			p.Add(phi, identity)
+			if i == 0 {
+				p.Legend.Add("phi(x)", phi)
+				p.Legend.Add("y = x", identity)
+			}
+		}
+		if err := addIterations(p, e, r, len(r.Steps)); err != nil {
+			return err
+		}
+	}
+
+	if len(roots) > 0 {
+		xs := make(plotter.XYs, len(roots))
+		for i, r := range roots {
+			xs[i].X = r.X
+		}
+		root, err := plotter.NewScatter(xs)
+		if err != nil {
+			return err
+		}
+		root.Shape = draw.CrossGlyph{}
+		root.Color = color.RGBA{R: 255, A: 255}
+		root.Radius = vg.Points(6)
+		p.Add(root)
+		p.Legend.Add("roots", root)
+	}
 	p.Legend.ThumbnailWidth = 1 * vg.Inch
 
 	p.X.Min = a - 2
@@ -276,7 +1251,5 @@ func drawPlot(e Equation, a, b float64) {
 	p.Y.Min = -5
 	p.Y.Max = 5
 
-	if err := p.Save(7*vg.Inch, 7*vg.Inch, "function.png"); err != nil {
-		log.Fatal(err)
-	}
+	return p.Save(7*vg.Inch, 7*vg.Inch, filename)
 }
@@ -130,3 +130,75 @@ func isExact(x1, x2 []float64, eps float64) bool {
 	}
 	return false
 }
+
+var ErrSingular = errors.New("matrix is singular")
+
+// LinearSolver solves A * x = b, it must not change a and b
+type LinearSolver interface {
+	Solve(a [][]float64, b []float64) ([]float64, error)
+}
+
+// GaussSolver is Gaussian elimination with partial pivoting
+type GaussSolver struct{}
+
+// GaussSeidelSolver is the iterative method from Compute, it needs a matrix
+// that can be made diagonally dominant
+type GaussSeidelSolver struct {
+	Accuracy float64
+}
+
+func (GaussSolver) Solve(a [][]float64, b []float64) ([]float64, error) {
+	n := len(a)
+	matrix := augment(a, b)
+
+	scale := 0.0
+	for i := range a {
+		for j := range a[i] {
+			scale = math.Max(scale, math.Abs(a[i][j]))
+		}
+	}
+
+	for k := 0; k < n; k++ {
+		pivot := k
+		for i := k + 1; i < n; i++ {
+			if math.Abs(matrix[i][k]) > math.Abs(matrix[pivot][k]) {
+				pivot = i
+			}
+		}
+		if math.Abs(matrix[pivot][k]) <= 1e-14*scale {
+			return nil, ErrSingular
+		}
+		matrix[k], matrix[pivot] = matrix[pivot], matrix[k]
+
+		for i := k + 1; i < n; i++ {
+			factor := matrix[i][k] / matrix[k][k]
+			for j := k; j <= n; j++ {
+				matrix[i][j] -= factor * matrix[k][j]
+			}
+		}
+	}
+
+	x := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		x[i] = matrix[i][n]
+		for j := i + 1; j < n; j++ {
+			x[i] -= matrix[i][j] * x[j]
+		}
+		x[i] /= matrix[i][i]
+	}
+
+	return x, nil
+}
+
+func (s GaussSeidelSolver) Solve(a [][]float64, b []float64) ([]float64, error) {
+	return Compute(augment(a, b), s.Accuracy)
+}
+
+// augment copies a and appends b as the last column
+func augment(a [][]float64, b []float64) [][]float64 {
+	matrix := make([][]float64, len(a))
+	for i := range a {
+		matrix[i] = append(append([]float64{}, a[i]...), b[i])
+	}
+	return matrix
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+// Polynomial holds coefficients from the highest power down to the constant term
+type Polynomial []float64
+
+type PolynomialRoot struct {
+	Z            complex128
+	Multiplicity int
+	Residual     float64
+}
+
+const (
+	aberthLimit = 500
+	// the unit roundoff of float64
+	roundoff = 0x1p-53
+	// computed roots of a multiple root are taken as one within this many
+	// times their expected scatter
+	clusterSlack = 10
+)
+
+func (p Polynomial) Degree() int {
+	return len(p.trim()) - 1
+}
+
+func (p Polynomial) Eval(z complex128) complex128 {
+	var ans complex128
+	for _, c := range p {
+		ans = ans*z + complex(c, 0)
+	}
+	return ans
+}
+
+func (p Polynomial) Derivative() Polynomial {
+	n := len(p) - 1
+	if n < 1 {
+		return Polynomial{0}
+	}
+
+	d := make(Polynomial, n)
+	for i := 0; i < n; i++ {
+		d[i] = p[i] * float64(n-i)
+	}
+	return d
+}
+
+func (p Polynomial) trim() Polynomial {
+	for len(p) > 1 && p[0] == 0 {
+		p = p[1:]
+	}
+	return p
+}
+
+// PolynomialRoots finds all complex roots with the Aberth method, merges
+// clusters into multiple roots and polishes each of them with Newton's method
+func PolynomialRoots(p Polynomial) ([]PolynomialRoot, error) {
+	p = p.trim()
+	n := p.Degree()
+	if n < 1 {
+		return nil, errors.New("polynomial degree must be at least 1")
+	}
+
+	d := p.Derivative()
+	z := aberth(p, d)
+
+	var roots []PolynomialRoot
+	used := make([]bool, n)
+	for i := 0; i < n; i++ {
+		if used[i] {
+			continue
+		}
+
+		center, members := cluster(p, z, used, i)
+		m := len(members)
+		for _, j := range members {
+			used[j] = true
+		}
+
+		// a real multiple root scatters into the complex plane, its center
+		// is real within the scatter
+		if math.Abs(imag(center)) <= clusterSlack*rootSpread(p, center, m) {
+			center = complex(real(center), 0)
+		}
+
+		// a root of multiplicity m is a simple root of the (m-1)-th derivative
+		q := p
+		for k := 1; k < m; k++ {
+			q = q.Derivative()
+		}
+		center = polish(q, center)
+
+		roots = append(roots, PolynomialRoot{Z: cleanComplex(center), Multiplicity: m})
+	}
+
+	pairConjugates(roots)
+	for i := range roots {
+		roots[i].Residual = cmplx.Abs(p.Eval(roots[i].Z))
+	}
+
+	sort.Slice(roots, func(i, j int) bool {
+		if math.Abs(real(roots[i].Z)-real(roots[j].Z)) > 1e-9*math.Max(1, cmplx.Abs(roots[i].Z)) {
+			return real(roots[i].Z) < real(roots[j].Z)
+		}
+		return imag(roots[i].Z) < imag(roots[j].Z)
+	})
+
+	return roots, nil
+}
+
+// cluster returns the center and the points of the largest group around
+// z[i] that fits in the scatter of a root of that multiplicity
+func cluster(p Polynomial, z []complex128, used []bool, i int) (complex128, []int) {
+	var near []int
+	for j := range z {
+		if !used[j] {
+			near = append(near, j)
+		}
+	}
+	sort.Slice(near, func(a, b int) bool { return cmplx.Abs(z[near[a]]-z[i]) < cmplx.Abs(z[near[b]]-z[i]) })
+
+	for m := len(near); m > 1; m-- {
+		var center complex128
+		for _, j := range near[:m] {
+			center += z[j]
+		}
+		center /= complex(float64(m), 0)
+
+		r := clusterSlack * rootSpread(p, center, m)
+		fits := true
+		for _, j := range near[:m] {
+			fits = fits && cmplx.Abs(z[j]-center) <= r
+		}
+		if fits {
+			return center, near[:m]
+		}
+	}
+	return z[i], []int{i}
+}
+
+// rootSpread is how far rounding scatters the computed roots of a root of
+// multiplicity m at z. Rounding changes p by about u * sum |c_k| |z|^k, and
+// near the root p(z + h) ~ p^(m)(z) / m! * h^m. |z| is taken at least 1, at
+// a root near 0 the sum would be the constant term, which is about 0.
+func rootSpread(p Polynomial, z complex128, m int) float64 {
+	scale := math.Max(1, cmplx.Abs(z))
+	size := 0.0
+	for _, c := range p {
+		size = size*scale + math.Abs(c)
+	}
+
+	q := p
+	for k := 1; k <= m; k++ {
+		q = q.Derivative()
+	}
+	lead := cmplx.Abs(q.Eval(z))
+	for k := 2; k <= m; k++ {
+		lead /= float64(k)
+	}
+
+	return math.Pow(roundoff*size/lead, 1/float64(m))
+}
+
+// pairConjugates makes the complex roots of a real polynomial come in exact
+// conjugate pairs
+func pairConjugates(roots []PolynomialRoot) {
+	paired := make([]bool, len(roots))
+	for i, r := range roots {
+		if imag(r.Z) <= 0 || paired[i] {
+			continue
+		}
+
+		best := -1
+		for j, s := range roots {
+			if imag(s.Z) >= 0 || paired[j] || s.Multiplicity != r.Multiplicity {
+				continue
+			}
+			if best < 0 || cmplx.Abs(s.Z-cmplx.Conj(r.Z)) < cmplx.Abs(roots[best].Z-cmplx.Conj(r.Z)) {
+				best = j
+			}
+		}
+		if best < 0 {
+			continue
+		}
+
+		z := (r.Z + cmplx.Conj(roots[best].Z)) / 2
+		roots[i].Z, roots[best].Z = z, cmplx.Conj(z)
+		paired[i], paired[best] = true, true
+	}
+}
+
+func aberth(p, d Polynomial) []complex128 {
+	n := p.Degree()
+
+	// Cauchy bound: every root lies inside this circle
+	radius := 0.0
+	for _, c := range p[1:] {
+		radius = math.Max(radius, math.Abs(c/p[0]))
+	}
+	radius++
+
+	z := make([]complex128, n)
+	for k := range z {
+		z[k] = cmplx.Rect(radius, 2*math.Pi*float64(k)/float64(n)+0.4)
+	}
+
+	for iterations := 0; iterations < aberthLimit; iterations++ {
+		maxStep := 0.0
+		for k := range z {
+			ratio := p.Eval(z[k]) / d.Eval(z[k])
+
+			var sum complex128
+			for j := range z {
+				if j != k {
+					sum += 1 / (z[k] - z[j])
+				}
+			}
+
+			w := ratio / (1 - ratio*sum)
+			if cmplx.IsNaN(w) || cmplx.IsInf(w) {
+				continue
+			}
+			z[k] -= w
+			maxStep = math.Max(maxStep, cmplx.Abs(w)/math.Max(1, cmplx.Abs(z[k])))
+		}
+
+		if maxStep < 1e-14 {
+			break
+		}
+	}
+
+	return z
+}
+
+// cleanComplex drops the parts that are only rounding noise
+func cleanComplex(z complex128) complex128 {
+	tol := 1e-12 * math.Max(1, cmplx.Abs(z))
+	if math.Abs(real(z)) < tol {
+		z = complex(0, imag(z))
+	}
+	if math.Abs(imag(z)) < tol {
+		z = complex(real(z), 0)
+	}
+	return z
+}
+
+func polish(p Polynomial, z complex128) complex128 {
+	d := p.Derivative()
+	for i := 0; i < 50; i++ {
+		dz := p.Eval(z) / d.Eval(z)
+		if cmplx.IsNaN(dz) || cmplx.IsInf(dz) {
+			break
+		}
+		z -= dz
+		if cmplx.Abs(dz) <= 1e-15*math.Max(1, cmplx.Abs(z)) {
+			break
+		}
+	}
+	return z
+}
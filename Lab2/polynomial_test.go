@@ -0,0 +1,42 @@
+package main
+
+import (
+	"math/cmplx"
+	"testing"
+)
+
+func TestPolynomialRoots(t *testing.T) {
+	type root struct {
+		z            complex128
+		multiplicity int
+	}
+	tests := []struct {
+		p     Polynomial
+		roots []root
+	}{
+		{Polynomial{1, 0, -3, 2}, []root{{-2, 1}, {1, 2}}},
+		// a multiple root at 0, where the constant term gives no scale
+		{Polynomial{1, 0, 0, 0, 0}, []root{{0, 4}}},
+		{Polynomial{1, -1, 0, 0}, []root{{0, 2}, {1, 1}}},
+		{Polynomial{1, -5, 10, -10, 5, -1}, []root{{1, 5}}},
+		{Polynomial{1, 0, 2, 0, 1}, []root{{-1i, 2}, {1i, 2}}},
+	}
+
+	for _, tt := range tests {
+		roots, err := PolynomialRoots(tt.p)
+		if err != nil {
+			t.Errorf("%v: %v", tt.p, err)
+			continue
+		}
+		if len(roots) != len(tt.roots) {
+			t.Errorf("%v: roots %v, want %v", tt.p, roots, tt.roots)
+			continue
+		}
+		for i, r := range tt.roots {
+			if cmplx.Abs(roots[i].Z-r.z) > 1e-6 || roots[i].Multiplicity != r.multiplicity {
+				t.Errorf("%v: root %v of multiplicity %d, want %v of multiplicity %d",
+					tt.p, roots[i].Z, roots[i].Multiplicity, r.z, r.multiplicity)
+			}
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// BigResult is a root found with big.Float, good to the asked number of digits
+type BigResult struct {
+	X          *big.Float
+	F          *big.Float
+	Iterations int
+}
+
+// BigMethod works on the parsed equation, since f and its derivatives have to be
+// evaluated in the same precision as x
+type BigMethod struct {
+	name          string
+	f             func(f *Expr, a, b float64, digits int) (BigResult, error)
+	multipleRoots bool
+}
+
+// precisionBits is the mantissa size for digits decimal digits, with some
+// bits to spare for the rounding of f
+func precisionBits(digits int) uint {
+	return uint(math.Ceil(float64(digits)*math.Log2(10))) + guardBits
+}
+
+// bigTolerance is 10^-digits * max(1, |x|), the error the root may have
+func bigTolerance(x *big.Float, digits int, prec uint) *big.Float {
+	tol := new(big.Float).SetPrec(prec).Abs(x)
+	if tol.Cmp(bigFloat(1, prec)) < 0 {
+		tol.SetFloat64(1)
+	}
+	scale, _ := new(big.Float).SetPrec(prec).SetString(fmt.Sprintf("1e-%d", digits))
+	return tol.Mul(tol, scale)
+}
+
+// BigNewtonMethod starts like NewtonMethod and uses the same modified step
+// for multiple roots, but takes no damped steps. Near a root of multiplicity
+// m, f rounded to p digits fixes x only to about p/m digits, so f is then
+// evaluated with m times the digits.
+func BigNewtonMethod(f *Expr, a, b float64, digits int) (BigResult, error) {
+	prec := precisionBits(digits)
+	d := f.Derivative(0)
+	d2 := d.Derivative(0)
+
+	start := b
+	if f.Eval([]float64{a})*d2.Eval([]float64{a}) > 0 {
+		start = a
+	}
+	x := bigFloat(start, prec)
+
+	m, candidate := 1, 1
+	for iterations := 0; iterations < limit; iterations++ {
+		fX, err := f.EvalBig([]*big.Float{x}, prec)
+		if err != nil {
+			return BigResult{}, err
+		}
+		if fX.Sign() == 0 {
+			return BigResult{X: x, F: fX, Iterations: iterations}, nil
+		}
+
+		dX, err := d.EvalBig([]*big.Float{x}, prec)
+		if err != nil {
+			return BigResult{}, err
+		}
+		if dX.Sign() == 0 {
+			return BigResult{}, &ZeroDerivativeError{X: []float64{bigToFloat(x)}}
+		}
+
+		// the estimate needs only a few digits
+		if d2X, err := d2.EvalBig([]*big.Float{x}, 64); err == nil {
+			if estimate := multiplicity(bigToFloat(fX), bigToFloat(dX), bigToFloat(d2X)); estimate == candidate {
+				m = estimate
+			} else {
+				candidate = estimate
+			}
+		}
+		if p := precisionBits(m * digits); p > prec {
+			prec = p
+			x = new(big.Float).SetPrec(prec).Set(x)
+		}
+
+		step := new(big.Float).SetPrec(prec).Quo(fX, dX)
+		step.Mul(step, bigFloat(float64(m), prec))
+		x = new(big.Float).SetPrec(prec).Sub(x, step)
+
+		if step.Abs(step).Cmp(bigTolerance(x, digits, prec)) <= 0 {
+			fX, err := f.EvalBig([]*big.Float{x}, prec)
+			if err != nil {
+				return BigResult{}, err
+			}
+			return BigResult{X: x, F: fX, Iterations: iterations}, nil
+		}
+	}
+
+	return BigResult{}, &DivergenceError{Iterations: limit, X: []float64{bigToFloat(x)}}
+}
+
+func BigBisectionMethod(f *Expr, a, b float64, digits int) (BigResult, error) {
+	prec := precisionBits(digits)
+	lo, hi := bigFloat(a, prec), bigFloat(b, prec)
+
+	fLo, err := f.EvalBig([]*big.Float{lo}, prec)
+	if err != nil {
+		return BigResult{}, err
+	}
+
+	iterations := 0
+	for {
+		x := new(big.Float).SetPrec(prec).Add(lo, hi)
+		x.SetMantExp(x, -1)
+
+		fX, err := f.EvalBig([]*big.Float{x}, prec)
+		if err != nil {
+			return BigResult{}, err
+		}
+
+		width := new(big.Float).SetPrec(prec).Sub(hi, lo)
+		if fX.Sign() == 0 || width.Cmp(bigTolerance(x, digits, prec)) <= 0 {
+			return BigResult{X: x, F: fX, Iterations: iterations}, nil
+		}
+
+		if fLo.Sign()*fX.Sign() <= 0 {
+			hi = x
+		} else {
+			lo, fLo = x, fX
+		}
+		iterations++
+	}
+}
+
+// findBigRoots is findRoots for the big.Float methods. A touching root is
+// kept only when f is zero to the asked number of digits there.
+func findBigRoots(f *Expr, m BigMethod, brackets []Bracket, digits int) ([]BigResult, []string, error) {
+	var roots []BigResult
+	var notes []string
+	var failed error
+	for _, br := range brackets {
+		if !br.touching {
+			r, err := m.f(f, br.a, br.b, digits)
+			if err != nil {
+				failed = fmt.Errorf("[%g, %g]: %w", br.a, br.b, err)
+				notes = append(notes, fmt.Sprintf("[%g, %g]: no root found: %v", br.a, br.b, err))
+				continue
+			}
+			roots = append(roots, r)
+			continue
+		}
+
+		g := f
+		if !m.multipleRoots {
+			g = f.Derivative(0)
+		}
+		r, err := m.f(g, br.a, br.b, digits)
+		if err == nil {
+			r.F, err = f.EvalBig([]*big.Float{r.X}, precisionBits(digits))
+		}
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("[%g, %g]: possible touching root discarded: %v", br.a, br.b, err))
+			continue
+		}
+		if new(big.Float).Abs(r.F).Cmp(bigTolerance(r.X, digits, precisionBits(digits))) > 0 {
+			notes = append(notes, fmt.Sprintf("[%g, %g]: possible touching root discarded, f(%s) = %s",
+				br.a, br.b, r.X.Text('g', digits), r.F.Text('g', 6)))
+			continue
+		}
+		roots = append(roots, r)
+	}
+
+	if len(roots) == 0 && failed != nil {
+		return nil, nil, failed
+	}
+	return roots, notes, nil
+}
+
+func printBigRoots(roots []BigResult, digits int, format string) error {
+	if len(roots) == 0 {
+		fmt.Println("No roots found")
+		return nil
+	}
+
+	t := Table{Header: []string{"#", "X", "f(x)", "Iterations"}}
+	for i, r := range roots {
+		t.Rows = append(t.Rows, []string{fmt.Sprint(i + 1), r.X.Text('g', digits), r.F.Text('g', 6), fmt.Sprint(r.Iterations)})
+	}
+	return t.Write(os.Stdout, format)
+}
+
+func formatBigRoots(roots []BigResult, digits int) string {
+	if len(roots) == 0 {
+		return "no roots"
+	}
+
+	xs := make([]string, len(roots))
+	for i, r := range roots {
+		xs[i] = r.X.Text('g', digits)
+	}
+	return strings.Join(xs, ", ")
+}
+
+func bigToFloat(x *big.Float) float64 {
+	v, _ := x.Float64()
+	return v
+}
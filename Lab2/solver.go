@@ -1,19 +1,45 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"math"
 )
 
-func ChordMethod(e Equation, a float64, b float64, eps float64) {
+const (
+	armijo          = 1e-4
+	minStep         = 1e-10
+	gridPoints      = 1000
+	maxMultiplicity = 10
+)
+
+var (
+	bracketHeader         = []string{"a", "b", "x", "f(a)", "f(b)", "f(x)", "|a-b|"}
+	newtonHeader          = []string{"x_i", "f(x_i)", "f'(x_i)", "x_i+1", "|x_i+1 - x_i|", "m"}
+	simpleIterationHeader = []string{"x_i", "x_i+1", "phi(x_i+1)", "f(x_i+1)", "|x_i+1 - x_i|"}
+	aitkenHeader          = []string{"x_n", "x_n+1", "Aitken x", "f(Aitken x)", "|change|"}
+	steffensenHeader      = []string{"x_i", "phi(x_i)", "x_i+1", "phi(phi(x_i))", "f(x_i+1)", "|x_i+1 - x_i|"}
+	secantHeader          = []string{"x_i-1", "x_i", "x_i+1", "f(x_i+1)", "|x_i+1 - x_i|"}
+	brentHeader           = []string{"a", "b", "x", "f(x)", "|a-b|"}
+)
+
+// ChordMethod returns the last chord point. On a convex or concave f one end
+// of the bracket never moves, so the bracket does not shrink and the method
+// stops when two chord points in a row are within eps.
+func ChordMethod(e Equation, a float64, b float64, eps float64) (Result, error) {
 	fA := e.f(a)
 	fB := e.f(b)
+	var steps [][]float64
 
-	iterations := 0
-	for math.Abs(fB-fA) > eps {
+	prev := math.Inf(1)
+	for iterations := 1; iterations <= limit; iterations++ {
 		x := (a*fB - b*fA) / (fB - fA)
 		fX := e.f(x)
+		steps = append(steps, []float64{a, b, x, fA, fB, fX, math.Abs(a - b)})
+
+		if fX == 0 || math.Abs(x-prev) <= eps {
+			return Result{X: x, F: fX, Iterations: iterations, Header: bracketHeader, Steps: steps, Visual: chords}, nil
+		}
 
 		if fA*fX < 0 {
 			b = x
@@ -22,99 +48,617 @@ func ChordMethod(e Equation, a float64, b float64, eps float64) {
 			a = x
 			fA = fX
 		}
-		iterations++
+		prev = x
 	}
-	fmt.Println("X =", (a+b)/2)
-	fmt.Println("f(x) =", e.f((a+b)/2))
-	fmt.Println("Number of iterations:", iterations)
 
+	return Result{}, &DivergenceError{Iterations: limit, X: []float64{prev}}
 }
 
-func NewtonMethod(e Equation, a float64, b float64, eps float64) {
-	var x0, x float64
+// NewtonMethod is damped: a step is halved until |f|^2 decreases enough
+// (Armijo rule), so it does not jump away where f' is small. Near a root of
+// multiplicity m plain steps converge only linearly, so m is estimated on
+// every step and, once two estimates agree, the step x - m*f/f' is used.
+func NewtonMethod(e Equation, a float64, b float64, eps float64) (Result, error) {
+	var x float64
 	if e.f(a)*e.derivative2(a) > 0 {
-		x0 = a
+		x = a
 	} else {
-		x0 = b
+		x = b
 	}
 
+	fX := e.f(x)
+	m, candidate := 1, 1
+	var steps [][]float64
+
+	iterations := 0
+	for ; iterations < limit; iterations++ {
+		if fX == 0 {
+			return Result{X: x, F: fX, Iterations: iterations, Header: newtonHeader, Steps: steps, Visual: tangents, Multiplicity: m}, nil
+		}
+
+		d := e.derivative(x)
+		if d == 0 {
+			return Result{}, &ZeroDerivativeError{X: []float64{x}}
+		}
+
+		if estimate := multiplicity(fX, d, e.derivative2(x)); estimate == candidate {
+			m = estimate
+		} else {
+			candidate = estimate
+		}
+		step := -float64(m) * fX / d
+
+		t := 1.0
+		next, fNext := x+step, e.f(x+step)
+		for fNext*fNext > (1-2*armijo*t)*fX*fX {
+			t /= 2
+			if t < minStep {
+				return Result{}, &StagnationError{X: []float64{x}, Norm: math.Abs(fX)}
+			}
+			next, fNext = x+t*step, e.f(x+t*step)
+		}
+
+		if math.IsNaN(next) || math.IsInf(next, 0) {
+			return Result{}, &DivergenceError{Iterations: iterations, X: []float64{next}}
+		}
+
+		steps = append(steps, []float64{x, fX, d, next, math.Abs(next - x), float64(m)})
+
+		// the full step is checked, a damped one is small far from the root too
+		done := math.Abs(step) < eps
+		x, fX = next, fNext
+		if done {
+			return Result{X: x, F: fX, Iterations: iterations + 1, Header: newtonHeader, Steps: steps, Visual: tangents, Multiplicity: m}, nil
+		}
+	}
+
+	return Result{}, &DivergenceError{Iterations: iterations, X: []float64{x}}
+}
+
+// multiplicity estimates m from u = f/d with d = f'. It has a simple root
+// where f has a root of multiplicity m, and there u' = 1 - f*d2/d^2 tends
+// to 1/m, d2 being the second derivative.
+func multiplicity(f, d, d2 float64) int {
+	u := 1 - f*d2/(d*d)
+	if math.IsNaN(u) || u <= 1/float64(maxMultiplicity+1) {
+		return 1
+	}
+
+	m := int(math.Round(1 / u))
+	if m < 1 {
+		return 1
+	}
+	return m
+}
+
+// SimpleIterationMethod iterates x = phi(x) = x + lambda*f(x) with
+// lambda = -1/max|f'|. It refuses to start unless q = max|phi'(x)| < 1 on
+// [a, b], and stops when |x_n - x_n-1| <= (1-q)/q * eps, which keeps the
+// error of x_n within eps.
+func SimpleIterationMethod(e Equation, a float64, b float64, eps float64) (Result, error) {
+	lambda, q, err := fixedPointForm(e, a, b)
+	if err != nil {
+		return Result{}, err
+	}
+	phi := func(x float64) float64 {
+		return x + lambda*e.f(x)
+	}
+	if q >= 1 {
+		return Result{}, &NoContractionError{Q: q}
+	}
+
+	stop := eps
+	if q > 0 {
+		stop = (1 - q) / q * eps
+	}
+
+	// from the middle the root is at most (b-a)/2 away
+	x0 := (a + b) / 2
+
+	var x float64
+	var steps [][]float64
+
 	iterations := 0
 	for {
-		x = x0 - e.f(x0)/e.derivative(x0)
-		if math.Abs(x-x0) < eps {
+		if iterations == limit {
+			return Result{}, &DivergenceError{Iterations: iterations, X: []float64{x0}}
+		}
+
+		x = phi(x0)
+		fX := e.f(x)
+		steps = append(steps, []float64{x0, x, x + lambda*fX, fX, math.Abs(x - x0)})
+		iterations++
+		if math.Abs(x-x0) <= stop {
 			break
 		}
 		x0 = x
+	}
+
+	return Result{X: x, F: e.f(x), Iterations: iterations, Header: simpleIterationHeader, Steps: steps, Q: q, Visual: cobweb, Phi: phi}, nil
+}
+
+// AitkenMethod runs the same iterations as SimpleIterationMethod and
+// extrapolates every three of them with Aitken's delta-squared process,
+// x' = x_n - (x_n+1 - x_n)^2 / (x_n+2 - 2x_n+1 + x_n). It stops when two
+// extrapolated values agree within eps.
+func AitkenMethod(e Equation, a float64, b float64, eps float64) (Result, error) {
+	lambda, q, err := fixedPointForm(e, a, b)
+	if err != nil {
+		return Result{}, err
+	}
+	phi := func(x float64) float64 {
+		return x + lambda*e.f(x)
+	}
+	if q >= 1 {
+		return Result{}, &NoContractionError{Q: q}
+	}
+
+	x0 := (a + b) / 2
+	x1 := phi(x0)
+	prev := x0
+	var steps [][]float64
+
+	iterations := 0
+	for {
+		if iterations == limit {
+			return Result{}, &DivergenceError{Iterations: iterations, X: []float64{x1}}
+		}
+
+		x2 := phi(x1)
+		x := x2
+		if denom := x2 - 2*x1 + x0; denom != 0 {
+			x = x0 - (x1-x0)*(x1-x0)/denom
+		}
+		fX := e.f(x)
+		steps = append(steps, []float64{x0, x1, x, fX, math.Abs(x - prev)})
 		iterations++
+		if fX == 0 || math.Abs(x-prev) <= eps {
+			return Result{X: x, F: fX, Iterations: iterations, Header: aitkenHeader, Steps: steps, Q: q, Visual: cobweb, Phi: phi}, nil
+		}
+
+		x0, x1, prev = x1, x2, x
+	}
+}
+
+// SteffensenMethod restarts the iterations from every Aitken value, which
+// makes them converge quadratically. It needs no contraction, only a good
+// enough starting point.
+func SteffensenMethod(e Equation, a float64, b float64, eps float64) (Result, error) {
+	lambda, _, err := fixedPointForm(e, a, b)
+	if err != nil {
+		return Result{}, err
+	}
+	phi := func(x float64) float64 {
+		return x + lambda*e.f(x)
+	}
+
+	x0 := (a + b) / 2
+	var steps [][]float64
+
+	iterations := 0
+	for ; iterations < limit; iterations++ {
+		x1 := phi(x0)
+		x2 := phi(x1)
+		denom := x2 - 2*x1 + x0
+		if denom == 0 {
+			return Result{X: x2, F: e.f(x2), Iterations: iterations, Header: steffensenHeader, Steps: steps, Visual: points}, nil
+		}
+
+		x := x0 - (x1-x0)*(x1-x0)/denom
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			break
+		}
+
+		fX := e.f(x)
+		steps = append(steps, []float64{x0, x1, x, x2, fX, math.Abs(x - x0)})
+		if fX == 0 || math.Abs(x-x0) <= eps {
+			return Result{X: x, F: fX, Iterations: iterations + 1, Header: steffensenHeader, Steps: steps, Visual: points}, nil
+		}
+		x0 = x
 	}
 
-	fmt.Println("X =", x)
-	fmt.Println("f(x) =", e.f(x))
-	fmt.Println("Number of iterations:", iterations)
+	return Result{}, &DivergenceError{Iterations: iterations, X: []float64{x0}}
 }
 
-func SimpleIterationMethod(e Equation, a float64, b float64, eps float64) {
+// fixedPointForm writes f(x) = 0 as x = phi(x) = x + lambda*f(x) with
+// lambda = -1/max|f'| on [a, b]. It returns lambda and q = max|phi'(x)| there.
+func fixedPointForm(e Equation, a, b float64) (float64, float64, error) {
 	max := e.derivative(a)
-	maxAbs := math.Abs(max)
-	for i := a; i < b; i += eps {
-		if math.Abs(e.derivative(i)) > maxAbs {
-			max = e.derivative(i)
-			maxAbs = math.Abs(max)
+	for i := 1; i <= gridPoints; i++ {
+		d := e.derivative(a + (b-a)*float64(i)/gridPoints)
+		if math.Abs(d) > math.Abs(max) {
+			max = d
 		}
 	}
+	if max == 0 {
+		return 0, 0, &ZeroDerivativeError{X: []float64{a, b}}
+	}
 
-	x0 := a
+	lambda := -1 / max
 
-	var x float64
+	q := 0.0
+	for i := 0; i <= gridPoints; i++ {
+		x := a + (b-a)*float64(i)/gridPoints
+		q = math.Max(q, math.Abs(1+lambda*e.derivative(x)))
+	}
+
+	return lambda, q, nil
+}
+
+// NewtonMethodSystem is damped like NewtonMethod, with the Armijo rule on ||F||^2
+func NewtonMethodSystem(s System, x0 []float64, eps float64, ls LinearSolver) (SystemResult, error) {
+	n := len(x0)
+	x := make([]float64, n)
+	copy(x, x0)
+
+	fX := evalSystem(s, x)
+	evaluations := 1
+	path := [][]float64{x}
 
+	iterations := 0
+	for ; iterations < limit; iterations++ {
+		jacobian := systemJacobian(s, x)
+		evaluations += jacobianCost(s, n)
+
+		solve, err := newtonStep(ls, jacobian, fX, x)
+		if err != nil {
+			return SystemResult{}, err
+		}
+
+		norm := dot(fX, fX)
+		t := 1.0
+		next := make([]float64, n)
+		var fNext []float64
+		for {
+			for i := range x {
+				next[i] = x[i] + t*solve[i]
+			}
+			fNext = evalSystem(s, next)
+			evaluations++
+
+			if dot(fNext, fNext) <= (1-2*armijo*t)*norm {
+				break
+			}
+			t /= 2
+			if t < minStep {
+				return SystemResult{}, &StagnationError{X: x, Norm: math.Sqrt(norm)}
+			}
+		}
+
+		for _, item := range next {
+			if math.IsNaN(item) || math.IsInf(item, 0) {
+				return SystemResult{}, &DivergenceError{Iterations: iterations, X: next}
+			}
+		}
+
+		done := maxAbs(solve) < eps && maxAbs(sub(fNext, fX)) < eps
+		x, fX = next, fNext
+		path = append(path, x)
+		if done {
+			return SystemResult{X: x, Iterations: iterations + 1, Evaluations: evaluations, Path: path}, nil
+		}
+	}
+
+	return SystemResult{}, &DivergenceError{Iterations: iterations, X: x}
+}
+
+// SimpleIterationMethodSystem iterates x = phi(x), the contraction condition
+// is checked by the caller with contractionFactor
+func SimpleIterationMethodSystem(s System, x0 []float64, eps float64, _ LinearSolver) (SystemResult, error) {
+	if s.phi == nil {
+		return SystemResult{}, errors.New("phi(x) is not given for this system")
+	}
+
+	x := make([]float64, len(x0))
+	copy(x, x0)
+	diff := make([]float64, len(x0))
+	path := [][]float64{x}
+
+	evaluations := 0
 	iterations := 0
 	for {
-		lambda := -1 / max
-		if e.derivative(x0)*max < 0 {
-			lambda = -lambda
+		if iterations == limit {
+			return SystemResult{}, &DivergenceError{Iterations: iterations, X: x}
 		}
-		phi := func(x0 float64) float64 {
-			return x0 + lambda*e.f(x0)
+
+		next := make([]float64, len(x))
+		for i, phi := range s.phi {
+			next[i] = phi(x)
 		}
-		x = phi(x0)
-		if math.Abs(x-x0) < eps && math.Abs(e.f(x)) < eps {
+		evaluations++
+		iterations++
+
+		for i := range x {
+			diff[i] = math.Abs(next[i] - x[i])
+		}
+		x = next
+		path = append(path, x)
+
+		if maxAbs(diff) <= eps {
 			break
 		}
-		x0 = x
+	}
+
+	return SystemResult{X: x, Iterations: iterations, Evaluations: evaluations, Error: diff, Path: path}, nil
+}
+
+// contractionFactor estimates max||phi'(x)|| over the box on a grid, using the
+// row-sum norm of the jacobian of phi
+func contractionFactor(s System, box [][2]float64) (float64, error) {
+	if s.phi == nil {
+		return 0, errors.New("phi(x) is not given for this system")
+	}
+
+	n := len(box)
+	points := int(math.Pow(10000, 1/float64(n)))
+	if points < 2 {
+		points = 2
+	}
+	phi := System{f: s.phi}
+
+	q := 0.0
+	idx := make([]int, n)
+	x := make([]float64, n)
+	for {
+		for i := range x {
+			x[i] = box[i][0] + (box[i][1]-box[i][0])*float64(idx[i])/float64(points-1)
+		}
+
+		for _, row := range systemJacobian(phi, x) {
+			sum := 0.0
+			for _, item := range row {
+				sum += math.Abs(item)
+			}
+			if math.IsNaN(sum) {
+				return 0, fmt.Errorf("phi(x) is not defined at %v", x)
+			}
+			q = math.Max(q, sum)
+		}
+
+		// next grid point, like an odometer
+		i := 0
+		for i < n && idx[i] == points-1 {
+			idx[i] = 0
+			i++
+		}
+		if i == n {
+			break
+		}
+		idx[i]++
+	}
+
+	return q, nil
+}
+
+// newtonStep solves J * dx = -F. A singular jacobian is reported as a zero
+// derivative at x, other failures of the linear solver are passed on.
+func newtonStep(ls LinearSolver, jacobian [][]float64, fX []float64, x []float64) ([]float64, error) {
+	if ls == nil {
+		ls = GaussSolver{}
+	}
+
+	b := make([]float64, len(fX))
+	for i := range fX {
+		b[i] = -fX[i]
+	}
+
+	dx, err := ls.Solve(jacobian, b)
+	if errors.Is(err, ErrSingular) {
+		return nil, &ZeroDerivativeError{X: x}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("linear solver: %w", err)
+	}
+	return dx, nil
+}
+
+func evalSystem(s System, x []float64) []float64 {
+	fX := make([]float64, len(s.f))
+	for i, f := range s.f {
+		fX[i] = f(x)
+	}
+	return fX
+}
+
+// jacobianCost is the price of one jacobian in evaluations of F: n columns
+// when it is analytic and 2n when it comes from central differences
+func jacobianCost(s System, n int) int {
+	if s.jacobian != nil {
+		return n
+	}
+	return 2 * n
+}
+
+// systemJacobian uses the analytic jacobian when the system has one and
+// central differences otherwise
+func systemJacobian(s System, x []float64) [][]float64 {
+	if s.jacobian != nil {
+		return s.jacobian(x)
+	}
+
+	n := len(x)
+	jacobian := make([][]float64, len(s.f))
+	for i := range jacobian {
+		jacobian[i] = make([]float64, n)
+	}
+
+	xh := make([]float64, n)
+	copy(xh, x)
+	for j := 0; j < n; j++ {
+		h := 1e-6 * math.Max(1, math.Abs(x[j]))
+
+		xh[j] = x[j] + h
+		fPlus := evalSystem(s, xh)
+		xh[j] = x[j] - h
+		fMinus := evalSystem(s, xh)
+		xh[j] = x[j]
+
+		for i := range s.f {
+			jacobian[i][j] = (fPlus[i] - fMinus[i]) / (2 * h)
+		}
+	}
+
+	return jacobian
+}
+
+func BisectionMethod(e Equation, a float64, b float64, eps float64) (Result, error) {
+	fA := e.f(a)
+	fB := e.f(b)
+	var steps [][]float64
+
+	iterations := 0
+	for math.Abs(b-a) > eps {
+		if iterations == limit {
+			return Result{}, &DivergenceError{Iterations: iterations, X: []float64{(a + b) / 2}}
+		}
+
+		x := (a + b) / 2
+		fX := e.f(x)
+		steps = append(steps, []float64{a, b, x, fA, fB, fX, math.Abs(a - b)})
+
+		if fA*fX <= 0 {
+			b = x
+			fB = fX
+		} else {
+			a = x
+			fA = fX
+		}
 		iterations++
 	}
 
-	fmt.Println("X =", x)
-	fmt.Println("f(x) =", e.f(x))
-	fmt.Println("Number of iterations:", iterations)
+	x := (a + b) / 2
+	return Result{X: x, F: e.f(x), Iterations: iterations, Header: bracketHeader, Steps: steps, Visual: points}, nil
 }
 
-func NewtonMethodSystem(s System, x0 float64, y0 float64, eps float64) {
-	var x, y float64
+func SecantMethod(e Equation, a float64, b float64, eps float64) (Result, error) {
+	x0, x1 := a, b
+	f0, f1 := e.f(x0), e.f(x1)
+	var steps [][]float64
 
 	iterations := 0
-	for {
-		solve, err := Compute(s.jacob(x0, y0), 0.001)
-		if err != nil {
-			log.Fatal(err)
+	for math.Abs(x1-x0) > eps && f1 != f0 {
+		if iterations == limit {
+			return Result{}, &DivergenceError{Iterations: iterations, X: []float64{x1}}
 		}
 
-		x = x0 + solve[0]
-		y = y0 + solve[1]
+		x := x1 - f1*(x1-x0)/(f1-f0)
+		fX := e.f(x)
+		steps = append(steps, []float64{x0, x1, x, fX, math.Abs(x - x1)})
+
+		x0, f0 = x1, f1
+		x1, f1 = x, fX
+		iterations++
+	}
+
+	return Result{X: x1, F: f1, Iterations: iterations, Header: secantHeader, Steps: steps, Visual: secants}, nil
+}
+
+func IllinoisMethod(e Equation, a float64, b float64, eps float64) (Result, error) {
+	fA := e.f(a)
+	fB := e.f(b)
+
+	// side remembers which end was kept on the previous step,
+	// so the retained value can be halved when it repeats
+	side := 0
+	x := a
+	var steps [][]float64
+
+	iterations := 0
+	for math.Abs(b-a) > eps {
+		if iterations == limit {
+			return Result{}, &DivergenceError{Iterations: iterations, X: []float64{x}}
+		}
 
-		if solve[0] < eps && solve[1] < eps && math.Abs(s.f[0](x, y)-s.f[0](x0, y0)) < eps && math.Abs(s.f[1](x, y)-s.f[1](x0, y0)) < eps {
+		x = (a*fB - b*fA) / (fB - fA)
+		fX := e.f(x)
+		steps = append(steps, []float64{a, b, x, fA, fB, fX, math.Abs(a - b)})
+		if fX == 0 {
 			break
 		}
 
-		x0 = x
-		y0 = y
+		if fA*fX < 0 {
+			b = x
+			fB = fX
+			if side == -1 {
+				fA /= 2
+			}
+			side = -1
+		} else {
+			a = x
+			fA = fX
+			if side == 1 {
+				fB /= 2
+			}
+			side = 1
+		}
+		iterations++
+
+		if math.Abs(fX) < eps {
+			break
+		}
+	}
+
+	return Result{X: x, F: e.f(x), Iterations: iterations, Header: bracketHeader, Steps: steps, Visual: chords}, nil
+}
+
+func BrentMethod(e Equation, a float64, b float64, eps float64) (Result, error) {
+	fA := e.f(a)
+	fB := e.f(b)
+	if math.Abs(fA) < math.Abs(fB) {
+		a, b = b, a
+		fA, fB = fB, fA
+	}
+
+	c, fC := a, fA
+	d := b - a
+	bisected := true
+	var steps [][]float64
 
+	iterations := 0
+	for fB != 0 && math.Abs(b-a) > eps {
+		if iterations == limit {
+			return Result{}, &DivergenceError{Iterations: iterations, X: []float64{b}}
+		}
+
+		var s float64
+		if fA != fC && fB != fC {
+			// inverse quadratic interpolation
+			s = a*fB*fC/((fA-fB)*(fA-fC)) +
+				b*fA*fC/((fB-fA)*(fB-fC)) +
+				c*fA*fB/((fC-fA)*(fC-fB))
+		} else {
+			s = b - fB*(b-a)/(fB-fA)
+		}
+
+		lo, hi := (3*a+b)/4, b
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		if s < lo || s > hi ||
+			(bisected && math.Abs(s-b) >= math.Abs(b-c)/2) ||
+			(!bisected && math.Abs(s-b) >= math.Abs(c-d)/2) ||
+			(bisected && math.Abs(b-c) < eps) ||
+			(!bisected && math.Abs(c-d) < eps) {
+			s = (a + b) / 2
+			bisected = true
+		} else {
+			bisected = false
+		}
+
+		fS := e.f(s)
+		steps = append(steps, []float64{a, b, s, fS, math.Abs(a - b)})
+		d, c, fC = c, b, fB
+		if fA*fS < 0 {
+			b, fB = s, fS
+		} else {
+			a, fA = s, fS
+		}
+		if math.Abs(fA) < math.Abs(fB) {
+			a, b = b, a
+			fA, fB = fB, fA
+		}
 		iterations++
 	}
 
-	fmt.Println("X =", x)
-	fmt.Println("Y =", y)
-	fmt.Println("f1(x, y) =", s.f[0](x, y))
-	fmt.Println("f2(x, y) =", s.f[1](x, y))
-	fmt.Println("Number of iterations:", iterations)
+	return Result{X: b, F: fB, Iterations: iterations, Header: brentHeader, Steps: steps, Visual: points}, nil
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Write prints the table as aligned text, markdown or csv
+func (t Table) Write(w io.Writer, format string) error {
+	switch format {
+	case "", "text":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(t.Header, "\t"))
+		for _, row := range t.Rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	case "markdown", "md":
+		writeMarkdownRow(w, t.Header)
+		fmt.Fprintf(w, "|%s\n", strings.Repeat(" --- |", len(t.Header)))
+		for _, row := range t.Rows {
+			writeMarkdownRow(w, row)
+		}
+		return nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(t.Header)
+		cw.WriteAll(t.Rows)
+		return cw.Error()
+	}
+
+	return fmt.Errorf("unknown format %q (text, markdown or csv)", format)
+}
+
+func writeMarkdownRow(w io.Writer, row []string) {
+	cells := make([]string, len(row))
+	for i, cell := range row {
+		cells[i] = strings.ReplaceAll(cell, "|", "\\|")
+	}
+	fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+)
+
+// Verified root finding with the Krawczyk operator
+//
+//	K(X) = m - y*f(m) + (1 - y*f'(X)) * (X - m),  m = mid(X), y ~ 1/f'(m).
+//
+// Every root in X is also in K(X). So if K(X) and X do not meet, X has no
+// root, and if K(X) lies inside X, X has exactly one. Boxes that are neither
+// are bisected. All of it is evaluated in interval arithmetic, so the
+// answers are proofs and not estimates.
+
+const verifyLimit = 100000
+
+// Enclosure is an interval that holds a root. Unique is set when it is
+// proven that there is exactly one root, otherwise the box got too narrow
+// to tell, which happens at multiple and at very close roots.
+type Enclosure struct {
+	X      Interval
+	Unique bool
+}
+
+// VerifyRoots returns enclosures of all roots of f in [a, b]. The verified
+// ones are at most eps wide, the unverified ones are hulls of boxes at most
+// eps wide that lie within eps of each other. No enclosures is a proof that
+// [a, b] has no root.
+func VerifyRoots(f, derivative *Expr, a, b, eps float64) ([]Enclosure, error) {
+	var enclosures []Enclosure
+	boxes := []Interval{{a, b}}
+
+	for n := 0; len(boxes) > 0; n++ {
+		if n == verifyLimit {
+			return nil, fmt.Errorf("gave up after %d boxes", n)
+		}
+
+		x := boxes[len(boxes)-1]
+		boxes = boxes[:len(boxes)-1]
+
+		if f.EvalInterval([]Interval{x}).Excludes(0) {
+			continue
+		}
+
+		k, ok := krawczyk(f, derivative, x)
+		if ok {
+			if k.Hi < x.Lo || k.Lo > x.Hi {
+				continue
+			}
+
+			if k.Lo > x.Lo && k.Hi < x.Hi {
+				enclosures = append(enclosures, Enclosure{refine(f, derivative, k, eps), true})
+				continue
+			}
+
+			x, _ = x.Intersect(k)
+		}
+
+		if x.Width() <= eps {
+			enclosures = append(enclosures, Enclosure{x, false})
+			continue
+		}
+
+		// a little off the middle, so a root that is a round number does
+		// not end up on the border of two boxes
+		c := x.Lo + 0.4990234375*x.Width()
+		boxes = append(boxes, Interval{c, x.Hi}, Interval{x.Lo, c})
+	}
+
+	return mergeEnclosures(enclosures, eps), nil
+}
+
+// krawczyk returns K(X), and false when it tells nothing
+func krawczyk(f, derivative *Expr, x Interval) (Interval, bool) {
+	m := x.Mid()
+	y := 1 / derivative.Eval([]float64{m})
+	if math.IsNaN(y) || math.IsInf(y, 0) {
+		return Interval{}, false
+	}
+
+	fm := f.EvalInterval([]Interval{point(m)})
+	dx := derivative.EvalInterval([]Interval{x})
+	k := point(m).Sub(point(y).Mul(fm)).Add(point(1).Sub(point(y).Mul(dx)).Mul(x.Sub(point(m))))
+
+	return k, k.IsValid() && !math.IsInf(k.Lo, 0) && !math.IsInf(k.Hi, 0)
+}
+
+// refine applies K again while the enclosure keeps shrinking. Once K(X) is
+// inside X the root is unique in X and K(X) still holds it.
+func refine(f, derivative *Expr, x Interval, eps float64) Interval {
+	for x.Width() > eps {
+		k, ok := krawczyk(f, derivative, x)
+		if !ok {
+			break
+		}
+		next, ok := x.Intersect(k)
+		if !ok || next.Width() >= x.Width() {
+			break
+		}
+		x = next
+	}
+	return x
+}
+
+// mergeEnclosures sorts the enclosures and joins the unverified ones that
+// are at most eps apart. Near a multiple root f is so flat that rounding
+// hides its sign on a whole neighbourhood, which falls apart into many small
+// boxes next to each other. Their hull still holds every root the pieces may
+// hold.
+func mergeEnclosures(enclosures []Enclosure, eps float64) []Enclosure {
+	sort.Slice(enclosures, func(i, j int) bool {
+		return enclosures[i].X.Lo < enclosures[j].X.Lo
+	})
+
+	var merged []Enclosure
+	for _, e := range enclosures {
+		if n := len(merged); n > 0 && !e.Unique && !merged[n-1].Unique &&
+			e.X.Lo-merged[n-1].X.Hi <= eps {
+			merged[n-1].X.Hi = math.Max(merged[n-1].X.Hi, e.X.Hi)
+			continue
+		}
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+func printEnclosures(enclosures []Enclosure, a, b float64, format string) error {
+	if len(enclosures) == 0 {
+		fmt.Printf("Proven: there is no root in [%g, %g]\n", a, b)
+		return nil
+	}
+
+	t := Table{Header: []string{"#", "Lower bound", "Upper bound", "Width", "Status"}}
+	for i, e := range enclosures {
+		status := "exactly one root"
+		if !e.Unique {
+			status = "not verified, maybe a multiple root"
+		}
+		t.Rows = append(t.Rows, []string{
+			fmt.Sprint(i + 1),
+			fmt.Sprintf("%.17g", e.X.Lo),
+			fmt.Sprintf("%.17g", e.X.Hi),
+			fmt.Sprintf("%.3g", e.X.Width()),
+			status,
+		})
+	}
+	return t.Write(os.Stdout, format)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVerifyRoots(t *testing.T) {
+	const eps = 1e-6
+
+	type root struct {
+		x      float64
+		unique bool
+	}
+	tests := []struct {
+		equation string
+		a, b     float64
+		roots    []root
+	}{
+		{"sin(x)", -4, 4, []root{{-math.Pi, true}, {0, true}, {math.Pi, true}}},
+		{"x^3 - x + 4", -3, 3, []root{{-1.7963219032594415, true}}},
+		{"x^3 - x + 4", 0, 3, nil},
+		{"x^3 - 2x^2 + 4x - 8", 0, 5, []root{{2, true}}},
+		// 1 is a double root, its sign does not change
+		{"x^3 - 3x + 2", -3, 3, []root{{-2, true}, {1, false}}},
+	}
+
+	for _, tt := range tests {
+		f, err := ParseExpr(tt.equation)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		enclosures, err := VerifyRoots(f, f.Derivative(0), tt.a, tt.b, eps)
+		if err != nil {
+			t.Errorf("%s on [%g, %g]: %v", tt.equation, tt.a, tt.b, err)
+			continue
+		}
+		if len(enclosures) != len(tt.roots) {
+			t.Errorf("%s on [%g, %g]: %d enclosures %v, want %d", tt.equation, tt.a, tt.b, len(enclosures), enclosures, len(tt.roots))
+			continue
+		}
+
+		for i, e := range enclosures {
+			r := tt.roots[i]
+			if e.X.Excludes(r.x) {
+				t.Errorf("%s: %v does not hold the root %g", tt.equation, e.X, r.x)
+			}
+			if e.Unique != r.unique {
+				t.Errorf("%s: root %g unique = %v, want %v", tt.equation, r.x, e.Unique, r.unique)
+			}
+			if e.Unique && e.X.Width() > eps {
+				t.Errorf("%s: %v is wider than %g", tt.equation, e.X, eps)
+			}
+		}
+	}
+}
+
+func TestMergeEnclosures(t *testing.T) {
+	const eps = 1e-3
+
+	got := mergeEnclosures([]Enclosure{
+		{Interval{1.0005, 1.001}, false},
+		{Interval{1, 1.0005}, false},
+		// a gap of more than eps keeps it apart
+		{Interval{1.003, 1.0035}, false},
+		// a verified root is never joined
+		{Interval{1.0036, 1.0037}, true},
+	}, eps)
+
+	want := []Enclosure{
+		{Interval{1, 1.001}, false},
+		{Interval{1.003, 1.0035}, false},
+		{Interval{1.0036, 1.0037}, true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("mergeEnclosures = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("mergeEnclosures = %v, want %v", got, want)
+			break
+		}
+	}
+}
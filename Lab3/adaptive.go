@@ -0,0 +1,148 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Adaptive rules keep a partition of [a, b] where every part has its value
+// and error estimate. The part with the largest error is halved until the
+// errors add up to eps, so only the parts that fail are split. Checking the
+// sum rather than giving each half a half of eps lets the parts where f is
+// known only to rounding stay as they are once they are small enough.
+
+const (
+	// the error sum is trusted only from this many parts on, a single part
+	// of a periodic f may have all its points on zeros
+	minParts = 8
+	// maxParts is where the splitting gives up
+	maxParts = 1 << 16
+)
+
+// part is a part of the partition. fs keeps f at a, (3a + b)/4, the middle,
+// (a + 3b)/4 and b for the rules that use them again after the split.
+type part struct {
+	a, b, value, err float64
+	fs               [5]float64
+}
+
+// parts is a heap with the largest error on top
+type parts []part
+
+func (p parts) Len() int            { return len(p) }
+func (p parts) Less(i, j int) bool  { return p[i].err > p[j].err }
+func (p parts) Swap(i, j int)       { p[i], p[j] = p[j], p[i] }
+func (p *parts) Push(x interface{}) { *p = append(*p, x.(part)) }
+func (p *parts) Pop() interface{} {
+	old := *p
+	x := old[len(old)-1]
+	*p = old[:len(old)-1]
+	return x
+}
+
+// subdivide splits the parts starting with the first ones by halves. It
+// returns the value, the error estimate and the bounds of the parts.
+func subdivide(eps float64, first []part, halves func(p part) (part, part)) (float64, float64, []float64, error) {
+	h := &parts{}
+	ans, e := 0.0, 0.0
+	push := func(p part) {
+		// a NaN error is split first and stops the loop on the value check
+		if math.IsNaN(p.err) {
+			p.err = math.Inf(1)
+		}
+		heap.Push(h, p)
+		ans += p.value
+		e += p.err
+	}
+	for _, p := range first {
+		push(p)
+	}
+
+	// an error at the rounding level of the value is as good as it gets
+	for !(e <= math.Max(eps, 1e-15*math.Abs(ans))) {
+		p := heap.Pop(h).(part)
+		if !isFinite(p.value) {
+			return 0, 0, nil, fmt.Errorf("the sum on [%g, %g] is %v", p.a, p.b, p.value)
+		}
+		if m := p.a + (p.b-p.a)/2; h.Len() >= maxParts || m <= p.a || m >= p.b {
+			return 0, 0, nil, fmt.Errorf("no convergence on [%g, %g] with %d parts", p.a, p.b, h.Len()+1)
+		}
+
+		ans -= p.value
+		e -= p.err
+		l, r := halves(p)
+		push(l)
+		push(r)
+	}
+
+	sort.Slice(*h, func(i, j int) bool { return (*h)[i].a < (*h)[j].a })
+	ans, e = 0, 0
+	points := []float64{(*h)[0].a}
+	for _, p := range *h {
+		ans += p.value
+		e += p.err
+		points = append(points, p.b)
+	}
+	return ans, e, points, nil
+}
+
+// AdaptiveSimpson compares Simpson's rule on a part with its sum on the two
+// halves, by Runge's rule the error of the halves is their difference / 15
+func AdaptiveSimpson(a, b, eps float64, f func(x float64) float64) (float64, float64, []float64, error) {
+	// the ends and middles of the first parts, shared by the neighbours
+	x := uniform(a, b, 2*minParts)
+	fs := make([]float64, len(x))
+	for i := range x {
+		fs[i] = f(x[i])
+	}
+
+	first := make([]part, minParts)
+	for i := range first {
+		first[i] = simpsonPart(f, x[2*i], x[2*i+2], fs[2*i], fs[2*i+1], fs[2*i+2])
+	}
+
+	return subdivide(eps, first, func(p part) (part, part) {
+		m := p.a + (p.b-p.a)/2
+		return simpsonPart(f, p.a, m, p.fs[0], p.fs[1], p.fs[2]), simpsonPart(f, m, p.b, p.fs[2], p.fs[3], p.fs[4])
+	})
+}
+
+func simpsonPart(f func(x float64) float64, a, b, fa, fm, fb float64) part {
+	m := a + (b-a)/2
+	flm, frm := f(a+(m-a)/2), f(m+(b-m)/2)
+	whole := (b - a) / 6 * (fa + 4*fm + fb)
+	left := (m - a) / 6 * (fa + 4*flm + fm)
+	right := (b - m) / 6 * (fm + 4*frm + fb)
+	return part{a, b, left + right, math.Abs(left+right-whole) / 15, [5]float64{fa, flm, fm, frm, fb}}
+}
+
+// AdaptiveKronrod checks every part by |K15 - G7|
+func AdaptiveKronrod(a, b, eps float64, f func(x float64) float64) (float64, float64, []float64, error) {
+	x := uniform(a, b, minParts)
+	first := make([]part, minParts)
+	for i := range first {
+		first[i] = kronrodPart(f, x[i], x[i+1])
+	}
+
+	return subdivide(eps, first, func(p part) (part, part) {
+		m := p.a + (p.b-p.a)/2
+		return kronrodPart(f, p.a, m), kronrodPart(f, m, p.b)
+	})
+}
+
+func kronrodPart(f func(x float64) float64, a, b float64) part {
+	k, g := kronrod15(a, b, f)
+	return part{a: a, b: b, value: k, err: math.Abs(k - g)}
+}
+
+// uniform returns the n + 1 bounds of n equal parts of [a, b]
+func uniform(a, b float64, n int) []float64 {
+	x := make([]float64, n+1)
+	for i := range x {
+		x[i] = a + float64(i)*(b-a)/float64(n)
+	}
+	x[n] = b
+	return x
+}
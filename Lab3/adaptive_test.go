@@ -0,0 +1,45 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAdaptive(t *testing.T) {
+	const eps = 1e-8
+
+	tests := []struct {
+		expression string
+		a, b, want float64
+	}{
+		// every point of a single part falls on a zero of f
+		{"sin(4x)^2", 0, math.Pi, math.Pi / 2},
+		{"sin(2x)^2", 0, math.Pi, math.Pi / 2},
+		{"x^4 - x^2", -1, 1, -4.0 / 15},
+		{"exp(x)", 0, 2, math.Exp(2) - 1},
+	}
+	rules := []struct {
+		name string
+		rule func(a, b, eps float64, f func(x float64) float64) (float64, float64, []float64, error)
+	}{
+		{"AdaptiveSimpson", AdaptiveSimpson},
+		{"AdaptiveKronrod", AdaptiveKronrod},
+	}
+
+	for _, tt := range tests {
+		e, err := ParseExpr(tt.expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range rules {
+			got, _, _, err := r.rule(tt.a, tt.b, eps, e.Eval)
+			if err != nil {
+				t.Errorf("%s(%s): %v", r.name, tt.expression, err)
+				continue
+			}
+			if math.Abs(got-tt.want) > eps {
+				t.Errorf("%s(%s) on [%g, %g] = %g, want %g", r.name, tt.expression, tt.a, tt.b, got, tt.want)
+			}
+		}
+	}
+}
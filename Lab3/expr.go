@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a parsed expression in x
+type Expr struct {
+	op   string
+	val  float64
+	args []*Expr
+}
+
+var functions = map[string]func(x float64) float64{
+	"sin":  math.Sin,
+	"cos":  math.Cos,
+	"tan":  math.Tan,
+	"asin": math.Asin,
+	"acos": math.Acos,
+	"atan": math.Atan,
+	"sinh": math.Sinh,
+	"cosh": math.Cosh,
+	"tanh": math.Tanh,
+	"exp":  math.Exp,
+	"ln":   math.Log,
+	"log":  math.Log,
+	"sqrt": math.Sqrt,
+	"abs":  math.Abs,
+}
+
+var constants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+// ParseExpr parses an expression like "x^2 + 3x - sin(x)"
+func ParseExpr(s string) (*Expr, error) {
+	p := parser{tokens: tokenize(s)}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in %q", p.tokens[p.pos], s)
+	}
+	return e, nil
+}
+
+func (e *Expr) Eval(x float64) float64 {
+	switch e.op {
+	case "num":
+		return e.val
+	case "var":
+		return x
+	case "neg":
+		return -e.args[0].Eval(x)
+	case "+":
+		return e.args[0].Eval(x) + e.args[1].Eval(x)
+	case "-":
+		return e.args[0].Eval(x) - e.args[1].Eval(x)
+	case "*":
+		return e.args[0].Eval(x) * e.args[1].Eval(x)
+	case "/":
+		return e.args[0].Eval(x) / e.args[1].Eval(x)
+	case "^":
+		return math.Pow(e.args[0].Eval(x), e.args[1].Eval(x))
+	}
+	return functions[e.op](e.args[0].Eval(x))
+}
+
+func tokenize(s string) []string {
+	var tokens []string
+	r := []rune(s)
+
+	for i := 0; i < len(r); {
+		switch {
+		case unicode.IsSpace(r[i]):
+			i++
+		case unicode.IsDigit(r[i]) || r[i] == '.':
+			j := i
+			for j < len(r) && (unicode.IsDigit(r[j]) || r[j] == '.') {
+				j++
+			}
+			// exponent part, e.g. 1e-3
+			if j+1 < len(r) && (r[j] == 'e' || r[j] == 'E') &&
+				(unicode.IsDigit(r[j+1]) || (j+2 < len(r) && (r[j+1] == '-' || r[j+1] == '+') && unicode.IsDigit(r[j+2]))) {
+				j += 2
+				for j < len(r) && unicode.IsDigit(r[j]) {
+					j++
+				}
+			}
+			tokens = append(tokens, string(r[i:j]))
+			i = j
+		case unicode.IsLetter(r[i]):
+			j := i
+			for j < len(r) && (unicode.IsLetter(r[j]) || unicode.IsDigit(r[j])) {
+				j++
+			}
+			tokens = append(tokens, string(r[i:j]))
+			i = j
+		default:
+			tokens = append(tokens, string(r[i]))
+			i++
+		}
+	}
+
+	return tokens
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *parser) expr() (*Expr, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "+" || p.peek() == "-" {
+		op := p.next()
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = &Expr{op: op, args: []*Expr{left, right}}
+	}
+
+	return left, nil
+}
+
+func (p *parser) term() (*Expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op := p.peek()
+		switch {
+		case op == "*" || op == "/":
+			p.next()
+		case op == "(" || (op != "" && (unicode.IsLetter([]rune(op)[0]) || unicode.IsDigit([]rune(op)[0]))):
+			// implicit multiplication, e.g. 2x or 3(x + 1)
+			op = "*"
+		default:
+			return left, nil
+		}
+
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &Expr{op: op, args: []*Expr{left, right}}
+	}
+}
+
+func (p *parser) unary() (*Expr, error) {
+	switch p.peek() {
+	case "-":
+		p.next()
+		arg, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &Expr{op: "neg", args: []*Expr{arg}}, nil
+	case "+":
+		p.next()
+		return p.unary()
+	}
+	return p.power()
+}
+
+func (p *parser) power() (*Expr, error) {
+	base, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek() == "^" {
+		p.next()
+		exp, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &Expr{op: "^", args: []*Expr{base, exp}}, nil
+	}
+
+	return base, nil
+}
+
+func (p *parser) primary() (*Expr, error) {
+	t := p.next()
+
+	switch {
+	case t == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case t == "(":
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return e, nil
+	case unicode.IsDigit([]rune(t)[0]) || t[0] == '.':
+		v, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t)
+		}
+		return &Expr{op: "num", val: v}, nil
+	case unicode.IsLetter([]rune(t)[0]):
+		name := strings.ToLower(t)
+
+		if _, ok := functions[name]; ok {
+			if p.next() != "(" {
+				return nil, fmt.Errorf("missing ( after %s", name)
+			}
+			arg, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if p.next() != ")" {
+				return nil, fmt.Errorf("missing )")
+			}
+			return &Expr{op: name, args: []*Expr{arg}}, nil
+		}
+
+		if v, ok := constants[name]; ok {
+			return &Expr{op: "num", val: v}, nil
+		}
+
+		if name == "x" {
+			return &Expr{op: "var"}, nil
+		}
+
+		return nil, fmt.Errorf("unknown name %q", t)
+	}
+
+	return nil, fmt.Errorf("unexpected %q", t)
+}
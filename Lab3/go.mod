@@ -2,7 +2,10 @@ module Lab3
 
 go 1.19
 
-require github.com/urfave/cli/v2 v2.25.0
+require (
+	github.com/urfave/cli/v2 v2.25.0
+	gopkg.in/yaml.v2 v2.4.0
+)
 
 require (
 	github.com/cpuguy83/go-md2man/v2 v2.0.2 // indirect
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Improper integrals. An infinite limit is moved to 1 by x = a + t/(1-t),
+// then f is probed on a grid for points where it is not finite or has a
+// spike that keeps growing when zoomed into. Near every such point c
+//
+//	|f(x)| ~ C |x - c|^-p,
+//
+// and the integral converges only for p < 1. The interval is split at the
+// singular points and each singular end is taken away by x = c + L*u^k with
+// k(1 - p) >= 2, which makes the integrand vanish at c, so the usual rules
+// work on it without ever evaluating f at c.
+
+const (
+	probePoints = 1000
+	zoomSteps   = 100
+	// a spike is a singularity when zooming in makes it this much higher
+	zoomGrowth = 1e3
+	// orders this close to 1 are taken as 1, where the integral diverges
+	orderSlack    = 1e-3
+	maxSubstitute = 32
+)
+
+// piece is a part of the integral, g integrated over [a, b]. x maps the
+// variable of g back to the original one for the messages.
+type piece struct {
+	a, b float64
+	g    func(t float64) float64
+	x    func(t float64) float64
+}
+
+// DivergenceError is returned when the integral does not exist
+type DivergenceError struct {
+	Reason string
+}
+
+func (e *DivergenceError) Error() string {
+	return "integral diverges: " + e.Reason
+}
+
+// splitImproper turns the integral of f over [a, b] into proper pieces. The
+// notes describe what was found on the way.
+func splitImproper(a, b float64, f func(x float64) float64) ([]piece, []string, error) {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return nil, nil, errors.New("invalid limits")
+	}
+	if a > b {
+		pieces, notes, err := splitImproper(b, a, f)
+		for i := range pieces {
+			g := pieces[i].g
+			pieces[i].g = func(t float64) float64 { return -g(t) }
+		}
+		return pieces, notes, err
+	}
+
+	identity := func(x float64) float64 { return x }
+
+	var pieces []piece
+	var notes []string
+	switch {
+	case a == b:
+		return nil, nil, nil
+	case math.IsInf(a, -1) && math.IsInf(b, 1):
+		right, rNotes, err := splitImproper(0, b, f)
+		if err != nil {
+			return nil, nil, err
+		}
+		left, lNotes, err := splitImproper(a, 0, f)
+		if err != nil {
+			return nil, nil, err
+		}
+		return append(left, right...), append(lNotes, rNotes...), nil
+	case math.IsInf(b, 1):
+		note, err := checkDecay(f, a, 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		notes = append(notes, note)
+		pieces = append(pieces, piece{0, 1, func(t float64) float64 {
+			return f(a+t/(1-t)) / ((1 - t) * (1 - t))
+		}, func(t float64) float64 { return a + t/(1-t) }})
+	case math.IsInf(a, -1):
+		note, err := checkDecay(f, b, -1)
+		if err != nil {
+			return nil, nil, err
+		}
+		notes = append(notes, note)
+		pieces = append(pieces, piece{0, 1, func(t float64) float64 {
+			return f(b-t/(1-t)) / ((1 - t) * (1 - t))
+		}, func(t float64) float64 { return b - t/(1-t) }})
+	default:
+		pieces = append(pieces, piece{a, b, f, identity})
+	}
+
+	var ans []piece
+	for _, p := range pieces {
+		split, splitNotes, err := removeSingularities(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		ans = append(ans, split...)
+		notes = append(notes, splitNotes...)
+	}
+	return ans, notes, nil
+}
+
+// checkDecay decides whether f decays fast enough towards infinity in the
+// direction dir, starting from the finite limit
+func checkDecay(f func(x float64) float64, from, dir float64) (string, error) {
+	scale := math.Max(1, math.Abs(from))
+	x1, x2 := 1e6*scale, 1e8*scale
+	m1, err := envelope(f, from+dir*x1, dir*x1)
+	if err != nil {
+		return "", err
+	}
+	m2, err := envelope(f, from+dir*x2, dir*x2)
+	if err != nil {
+		return "", err
+	}
+
+	name := "+Inf"
+	if dir < 0 {
+		name = "-Inf"
+	}
+
+	if m2 == 0 {
+		return fmt.Sprintf("f(x) vanishes towards %s, the integral converges", name), nil
+	}
+	q := math.Log(m1/m2) / math.Log(x2/x1)
+	switch {
+	case m1 == 0 || q <= 0:
+		return "", &DivergenceError{fmt.Sprintf("f(x) does not tend to zero towards %s", name)}
+	case oscillates(f, from+dir*x1, dir*x1):
+		// after x = t/(1-t) the waves get infinitely dense at t = 1
+		return "", fmt.Errorf("f(x) keeps changing sign towards %s with |f(x)| ~ |x|^-%.2f, "+
+			"the rules cannot follow it, integrate up to a finite limit", name, q)
+	case q <= 1+orderSlack:
+		return "", &DivergenceError{fmt.Sprintf("|f(x)| decays like |x|^-%.2f towards %s, slower than 1/|x|", q, name)}
+	}
+	return fmt.Sprintf("|f(x)| decays like |x|^-%.2f towards %s, the integral converges", q, name), nil
+}
+
+// removeSingularities finds the singular points of the piece and returns
+// the parts between them with the singular ends substituted away
+func removeSingularities(p piece) ([]piece, []string, error) {
+	h := (p.b - p.a) / probePoints
+	values := make([]float64, probePoints+1)
+	for i := range values {
+		values[i] = p.g(p.a + float64(i)*h)
+	}
+
+	var points []float64
+	for i, v := range values {
+		if isFinite(v) {
+			continue
+		}
+		// two non-finite values in a row are not a point but a whole part of
+		// the interval, where f overflows or is not defined
+		if (i > 0 && !isFinite(values[i-1])) || (i < probePoints && !isFinite(values[i+1])) {
+			x := p.x(p.a + float64(i)*h)
+			if math.IsNaN(v) {
+				return nil, nil, fmt.Errorf("f(x) is not defined at x = %g", x)
+			}
+			return nil, nil, &DivergenceError{fmt.Sprintf("f(x) overflows near x = %g", x)}
+		}
+		points = append(points, p.a+float64(i)*h)
+	}
+
+	for i := 1; i < probePoints; i++ {
+		v, left, right := math.Abs(values[i]), math.Abs(values[i-1]), math.Abs(values[i+1])
+		if !isFinite(v) || !isFinite(left) || !isFinite(right) || v < left || v < right || (v == left && v == right) {
+			continue
+		}
+		if c, ok := zoom(p.g, p.a+float64(i-1)*h, p.a+float64(i+1)*h, v); ok {
+			points = append(points, c)
+		}
+	}
+
+	sort.Float64s(points)
+	var singular []float64
+	for _, c := range points {
+		if n := len(singular); n == 0 || c-singular[n-1] > h/2 {
+			singular = append(singular, c)
+		}
+	}
+
+	// the order of every singular point, and where the piece is split
+	orders := map[float64]float64{}
+	var notes []string
+	for _, c := range singular {
+		order := math.Inf(-1)
+		for _, dir := range []float64{-1, 1} {
+			if (dir < 0 && c <= p.a) || (dir > 0 && c >= p.b) {
+				continue
+			}
+			o, err := singularOrder(p, c, dir)
+			if err != nil {
+				return nil, nil, err
+			}
+			order = math.Max(order, o)
+		}
+
+		switch {
+		case math.IsInf(order, 1):
+			return nil, nil, &DivergenceError{fmt.Sprintf("f(x) grows faster than any power near x = %g", p.x(c))}
+		case order >= 1-orderSlack:
+			return nil, nil, &DivergenceError{fmt.Sprintf("|f(x)| grows like |x - c|^-%.2f near c = %g", order, p.x(c))}
+		case order > orderSlack:
+			notes = append(notes, fmt.Sprintf("|f(x)| grows like |x - c|^-%.2f near c = %g, the integral converges", order, p.x(c)))
+		case !math.IsInf(p.x(c), 0):
+			notes = append(notes, fmt.Sprintf("f(x) is bounded near x = %g but not defined there", p.x(c)))
+		}
+		orders[c] = order
+	}
+
+	bounds := append([]float64{p.a}, singular...)
+	bounds = append(bounds, p.b)
+	var pieces []piece
+	for i := 1; i < len(bounds); i++ {
+		a, b := bounds[i-1], bounds[i]
+		if a == b {
+			continue
+		}
+		orderA, singularA := orders[a]
+		orderB, singularB := orders[b]
+
+		part := piece{a, b, p.g, p.x}
+		switch {
+		case singularA && singularB:
+			m := a + (b-a)/2
+			pieces = append(pieces, substitute(piece{a, m, p.g, p.x}, a, orderA), substitute(piece{m, b, p.g, p.x}, b, orderB))
+		case singularA:
+			pieces = append(pieces, substitute(part, a, orderA))
+		case singularB:
+			pieces = append(pieces, substitute(part, b, orderB))
+		default:
+			pieces = append(pieces, part)
+		}
+	}
+
+	return pieces, notes, nil
+}
+
+// zoom looks for the maximum of |g| on [a, b] with ternary search and
+// reports whether it is much higher than the spike v seen on the grid
+func zoom(g func(t float64) float64, a, b, v float64) (float64, bool) {
+	height := func(t float64) float64 {
+		y := math.Abs(g(t))
+		if math.IsNaN(y) {
+			return math.Inf(1)
+		}
+		return y
+	}
+
+	for i := 0; i < zoomSteps; i++ {
+		m1, m2 := a+(b-a)/3, b-(b-a)/3
+		if height(m1) < height(m2) {
+			a = m1
+		} else {
+			b = m2
+		}
+	}
+
+	c := a + (b-a)/2
+	return c, height(c) > zoomGrowth*math.Max(v, math.SmallestNonzeroFloat64)
+}
+
+// singularOrder estimates p of |g(t)| ~ |t - c|^-p on the side dir of c
+func singularOrder(p piece, c, dir float64) (float64, error) {
+	length := p.b - p.a
+	h1 := math.Max(1e-7*length, 1e-12*math.Abs(c))
+	h2 := 100 * h1
+
+	m1, err := envelope(p.g, c+dir*h1, dir*h1)
+	if err != nil {
+		return 0, fmt.Errorf("near x = %g: %w", p.x(c), err)
+	}
+	m2, err := envelope(p.g, c+dir*h2, dir*h2)
+	if err != nil {
+		return 0, fmt.Errorf("near x = %g: %w", p.x(c), err)
+	}
+
+	switch {
+	case math.IsInf(m1, 1):
+		return math.Inf(1), nil
+	case m1 == 0 || m2 == 0:
+		return 0, nil
+	}
+	return math.Log(m1/m2) / math.Log(h2/h1), nil
+}
+
+// envelope is the largest |g| on [from, from + width], so an oscillating g
+// is measured by its amplitude
+func envelope(g func(t float64) float64, from, width float64) (float64, error) {
+	max := 0.0
+	for i := 0; i < 16; i++ {
+		t := from + width*float64(i)/16
+		y := math.Abs(g(t))
+		if math.IsNaN(y) {
+			return 0, fmt.Errorf("f(x) is not defined at x = %g", t)
+		}
+		max = math.Max(max, y)
+	}
+	return max, nil
+}
+
+// oscillates reports whether g changes sign more than once on
+// [from, from + width]
+func oscillates(g func(t float64) float64, from, width float64) bool {
+	changes, sign := 0, 0.0
+	for i := 0; i < 64; i++ {
+		y := g(from + width*float64(i)/64)
+		if y == 0 {
+			continue
+		}
+		if s := math.Copysign(1, y); sign != 0 && s != sign {
+			changes++
+		}
+		sign = math.Copysign(1, y)
+	}
+	return changes > 1
+}
+
+// substitute removes the singular end c of the piece with x = c + L*u^k,
+// u in [0, 1]. The integrand is zero at u = 0 since it vanishes there.
+func substitute(p piece, c, order float64) piece {
+	k := 2.0
+	if order > 0 {
+		k = math.Min(maxSubstitute, math.Max(k, math.Ceil(2/(1-order))))
+	}
+
+	length, dir := p.b-p.a, 1.0
+	if c == p.b {
+		dir = -1
+	}
+
+	return piece{0, 1, func(u float64) float64 {
+		if u == 0 {
+			return 0
+		}
+		y := p.g(c+dir*length*math.Pow(u, k)) * k * length * math.Pow(u, k-1)
+		// so close to c that t rounds to c, the part left is below rounding
+		if !isFinite(y) {
+			return 0
+		}
+		return y
+	}, func(u float64) float64 { return p.x(c + dir*length*math.Pow(u, k)) }}
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
@@ -1,31 +1,60 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
 	"log"
 	"math"
 	"os"
 )
 
+// data is a task read from the file. The function is either one of the
+// built-in ones by its number or an expression in x.
+type data struct {
+	Method     int     `yaml:"method"`
+	Function   int     `yaml:"function"`
+	Expression string  `yaml:"expression"`
+	A          float64 `yaml:"a"`
+	B          float64 `yaml:"b"`
+	Eps        float64 `yaml:"eps"`
+	Order      int     `yaml:"order"`
+}
+
 type Function struct {
 	s string
 	f func(x float64) float64
 }
 
+// Method is a composite rule on n parts. num is 2^p - 1 for a rule of order
+// p, Runge's rule divides by it. A rule with its own error estimate gives it
+// by estimate, and then Runge's rule is not used. An adaptive method picks
+// the parts itself and returns them with the value and the error. A method
+// with a table returns all its rows, the last entry is the value.
 type Method struct {
-	name string
-	f    func(a, b float64, n int, f func(x float64) float64) float64
-	num  float64
+	name     string
+	f        func(a, b float64, n int, f func(x float64) float64) float64
+	num      float64
+	estimate func(a, b float64, n int, f func(x float64) float64) (float64, float64)
+	adaptive func(a, b, eps float64, f func(x float64) float64) (float64, float64, []float64, error)
+	table    func(a, b, eps float64, f func(x float64) float64) ([][]float64, error)
 }
 
 func main() {
+	gaussLegendre := GaussLegendre(defaultGaussOrder)
 	methods := []Method{
-		{"Left rectangle method", LeftRectangleMethod, 3},
-		{"Middle rectangle method", MiddleRectangleMethod, 3},
-		{"Right rectangle method", RightRectangleMethod, 3},
-		{"Trapezoidal method", TrapezoidalMethod, 3},
-		{"Simpson method", SimpsonMethod, 15},
+		{name: "Left rectangle method", f: LeftRectangleMethod, num: 1},
+		{name: "Middle rectangle method", f: MiddleRectangleMethod, num: 3},
+		{name: "Right rectangle method", f: RightRectangleMethod, num: 1},
+		{name: "Trapezoidal method", f: TrapezoidalMethod, num: 3},
+		{name: "Simpson method", f: SimpsonMethod, num: 15},
+		gaussLegendre,
+		{name: "Gauss-Kronrod G7-K15 method", f: KronrodMethod, num: math.Pow(2, 22) - 1, estimate: KronrodEstimate},
+		{name: "Adaptive Simpson method", f: SimpsonMethod, num: 15, adaptive: AdaptiveSimpson},
+		{name: "Adaptive Gauss-Kronrod method", f: KronrodMethod, num: math.Pow(2, 22) - 1, adaptive: AdaptiveKronrod},
+		{name: "Romberg method", f: TrapezoidalMethod, num: 3, table: Romberg},
 	}
 
 	functions := []Function{
@@ -55,40 +84,69 @@ func main() {
 			&cli.StringFlag{
 				Name:    "filename",
 				Aliases: []string{"f"},
-				Usage:   "Filename if not console input (default: data.yml)",
+				Usage:   "YAML or JSON filename if not console input (default: data.yml)",
+			},
+			&cli.BoolFlag{
+				Name:    "richardson",
+				Aliases: []string{"r"},
+				Usage:   "Also print the Richardson extrapolated value",
 			},
 		},
 		Action: func(cCtx *cli.Context) error {
-			var method, function int
-			var a, b, eps float64
+			var d data
 
-			for i, method := range methods {
-				fmt.Printf("%d. %s\n", i+1, method.name)
-			}
-			fmt.Scan(&method)
-			if method < 1 || method > len(methods) {
-				return fmt.Errorf("invalid method")
+			if cCtx.Bool("console-input") {
+				for i, method := range methods {
+					fmt.Printf("%d. %s\n", i+1, method.name)
+				}
+				fmt.Print("Choose method: ")
+				fmt.Scan(&d.Method)
+				if d.Method >= 1 && d.Method <= len(methods) && methods[d.Method-1].name == gaussLegendre.name {
+					fmt.Print("Enter number of nodes: ")
+					fmt.Scan(&d.Order)
+				}
+
+				for i, function := range functions {
+					fmt.Printf("%d. %s\n", i+1, function.s)
+				}
+				fmt.Print("Choose function: ")
+				fmt.Scan(&d.Function)
+
+				fmt.Print("Enter a: ")
+				fmt.Scan(&d.A)
+				fmt.Print("Enter b: ")
+				fmt.Scan(&d.B)
+				fmt.Print("Enter eps: ")
+				fmt.Scan(&d.Eps)
+			} else {
+				var err error
+				d, err = readData(cCtx.String("filename"))
+				if err != nil {
+					return err
+				}
 			}
 
-			for i, function := range functions {
-				fmt.Printf("%d. %s\n", i+1, function.s)
+			if d.Method < 1 || d.Method > len(methods) {
+				return fmt.Errorf("invalid method")
 			}
-			fmt.Print("Choose function: ")
-			fmt.Scan(&function)
-			if function < 1 || function > len(functions) {
-				return fmt.Errorf("invalid function")
+			if d.Eps <= 0 {
+				return errors.New("eps must be positive")
 			}
 
-			fmt.Print("Enter a: ")
-			fmt.Scan(&a)
-			fmt.Print("Enter b: ")
-			fmt.Scan(&b)
-			fmt.Print("Enter eps: ")
-			fmt.Scan(&eps)
+			f, err := chooseFunction(d, functions)
+			if err != nil {
+				return err
+			}
 
-			Solve(a, b, eps, methods[method-1], functions[function-1].f)
+			m := methods[d.Method-1]
+			if m.name == gaussLegendre.name && d.Order != 0 {
+				if err := checkGaussOrder(d.Order); err != nil {
+					return err
+				}
+				m = GaussLegendre(d.Order)
+			}
 
-			return nil
+			return Solve(d.A, d.B, d.Eps, m, f.f, cCtx.Bool("richardson"))
 		},
 	}
 
@@ -96,3 +154,37 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+func readData(filename string) (data, error) {
+	var d data
+
+	if len(filename) == 0 {
+		filename = "data.yml"
+	}
+
+	yamlFile, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return d, err
+	}
+
+	// JSON is valid YAML, so the same call reads both
+	err = yaml.Unmarshal(yamlFile, &d)
+	return d, err
+}
+
+// chooseFunction returns the parsed expression if there is one, otherwise
+// the built-in function by its number
+func chooseFunction(d data, functions []Function) (Function, error) {
+	if len(d.Expression) > 0 {
+		e, err := ParseExpr(d.Expression)
+		if err != nil {
+			return Function{}, err
+		}
+		return Function{d.Expression, e.Eval}, nil
+	}
+
+	if d.Function < 1 || d.Function > len(functions) {
+		return Function{}, errors.New("invalid function")
+	}
+	return functions[d.Function-1], nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"text/tabwriter"
+)
+
+const (
+	// the diagonal is trusted only from the row with 2^rombergMinK parts on,
+	// the first rows of a periodic f may have all their points on zeros
+	rombergMinK = 4
+	// rombergRows is where Romberg's method gives up, the last row has maxN parts
+	rombergRows = 25
+)
+
+// Romberg builds the Richardson table R. R[k][0] is the trapezoidal rule on
+// 2^k parts and R[k][j] = R[k][j-1] + (R[k][j-1] - R[k-1][j-1]) / (4^j - 1)
+// removes the h^(2j) term of its error. It stops when two entries of the
+// diagonal are within eps, but not before rombergMinK rows.
+func Romberg(a, b, eps float64, f func(x float64) float64) ([][]float64, error) {
+	rows := [][]float64{{(b - a) / 2 * (f(a) + f(b))}}
+
+	n := 1
+	for k := 1; k < rombergRows; k++ {
+		// the new points are the middles of the old parts
+		h := (b - a) / float64(2*n)
+		sum := 0.0
+		for i := 0; i < n; i++ {
+			sum += f(a + float64(2*i+1)*h)
+		}
+		n *= 2
+
+		row := []float64{rows[k-1][0]/2 + h*sum}
+		for j := 1; j <= k; j++ {
+			row = append(row, row[j-1]+(row[j-1]-rows[k-1][j-1])/(math.Pow(4, float64(j))-1))
+		}
+		rows = append(rows, row)
+
+		if !isFinite(row[k]) {
+			return nil, fmt.Errorf("the sum on [%g, %g] is %v", a, b, row[k])
+		}
+		if k >= rombergMinK && math.Abs(row[k]-rows[k-1][k-1]) <= eps {
+			return rows, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no convergence on [%g, %g] with n = %d", a, b, n)
+}
+
+func printRombergTable(rows [][]float64) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "n")
+	for j := range rows {
+		fmt.Fprintf(w, "\tR[k][%d]", j)
+	}
+	fmt.Fprintln(w)
+
+	for k, row := range rows {
+		fmt.Fprint(w, 1<<k)
+		for _, v := range row {
+			fmt.Fprintf(w, "\t%.12g", v)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
+
+// richardson improves the rule on n parts by the one on 2n parts, the error
+// of the finer one is about their difference / num
+func richardson(a, b float64, n int, m Method, f func(x float64) float64) float64 {
+	fine := m.f(a, b, 2*n, f)
+	return fine + (fine-m.f(a, b, n, f))/m.num
+}
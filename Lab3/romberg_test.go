@@ -0,0 +1,36 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRomberg(t *testing.T) {
+	const eps = 1e-8
+
+	tests := []struct {
+		expression string
+		a, b, want float64
+	}{
+		// the first rows agree on 0 before the rule sees the shape of f
+		{"x^4 - x^2", -1, 1, -4.0 / 15},
+		{"sin(2x)^2", 0, math.Pi, math.Pi / 2},
+		{"exp(x)", 0, 2, math.Exp(2) - 1},
+	}
+
+	for _, tt := range tests {
+		e, err := ParseExpr(tt.expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := Romberg(tt.a, tt.b, eps, e.Eval)
+		if err != nil {
+			t.Errorf("Romberg(%s): %v", tt.expression, err)
+			continue
+		}
+		last := rows[len(rows)-1]
+		if got := last[len(last)-1]; math.Abs(got-tt.want) > eps {
+			t.Errorf("Romberg(%s) on [%g, %g] = %g, want %g", tt.expression, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
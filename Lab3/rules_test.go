@@ -0,0 +1,46 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	const a, b, n = 1.0, 2.0, 8
+
+	tests := []struct {
+		name string
+		rule func(a, b float64, n int, f func(x float64) float64) float64
+		// the evaluations of f on n parts
+		evaluations int
+		// the rule is exact for polynomials up to this degree
+		degree int
+	}{
+		{"LeftRectangleMethod", LeftRectangleMethod, n, 0},
+		{"MiddleRectangleMethod", MiddleRectangleMethod, n, 1},
+		{"RightRectangleMethod", RightRectangleMethod, n, 0},
+		{"TrapezoidalMethod", TrapezoidalMethod, n + 1, 1},
+		{"SimpsonMethod", SimpsonMethod, n + 1, 3},
+	}
+
+	for _, tt := range tests {
+		// every sample is a point of [a, b], taken once
+		evaluations := 0
+		tt.rule(a, b, n, func(x float64) float64 {
+			evaluations++
+			if x < a || x > b {
+				t.Errorf("%s samples f(%g) outside [%g, %g]", tt.name, x, a, b)
+			}
+			return 1
+		})
+		if evaluations != tt.evaluations {
+			t.Errorf("%s evaluates f %d times on %d parts, want %d", tt.name, evaluations, n, tt.evaluations)
+		}
+
+		got := tt.rule(a, b, n, func(x float64) float64 { return math.Pow(x, float64(tt.degree)) })
+		want := (math.Pow(b, float64(tt.degree+1)) - math.Pow(a, float64(tt.degree+1))) / float64(tt.degree+1)
+		if math.Abs(got-want) > 1e-12 {
+			t.Errorf("%s of x^%d on [%g, %g] = %g, want %g", tt.name, tt.degree, a, b, got, want)
+		}
+	}
+}
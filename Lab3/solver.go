@@ -3,29 +3,167 @@ package main
 import (
 	"fmt"
 	"math"
+	"sort"
+	"strings"
 )
 
-func Solve(a, b, eps float64, m Method, f func(x float64) float64) {
+// maxN is where the doubling of n gives up
+const maxN = 1 << 24
+
+// Solve integrates f over [a, b], which may be improper. Every proper piece
+// gets its share of eps. With extrapolate the Richardson extrapolated value
+// is printed as well, its evaluations of f are not counted.
+func Solve(a, b, eps float64, m Method, f func(x float64) float64, extrapolate bool) error {
+	probes := 0
+	pieces, notes, err := splitImproper(a, b, func(x float64) float64 {
+		probes++
+		return f(x)
+	})
+	if err != nil {
+		return err
+	}
+	// the pieces go through the same counter, so the checks are only the
+	// evaluations made so far
+	checks := probes
+	for _, note := range notes {
+		fmt.Println(note)
+	}
+
+	evaluations := 0
+	var partition []float64
+	ans, errorEstimate, extrapolated, total := 0.0, 0.0, 0.0, 0
+	for _, p := range pieces {
+		g := p.g
+		counted := func(t float64) float64 {
+			evaluations++
+			return g(t)
+		}
+
+		share := eps / float64(len(pieces))
+		switch {
+		case m.table != nil:
+			rows, err := m.table(p.a, p.b, share, counted)
+			if err != nil {
+				return fmt.Errorf("%s: %w", m.name, err)
+			}
+			if len(pieces) > 1 {
+				fmt.Printf("[%g, %g]:\n", p.x(p.a), p.x(p.b))
+			}
+			printRombergTable(rows)
+
+			k := len(rows) - 1
+			ans += rows[k][k]
+			errorEstimate += math.Abs(rows[k][k] - rows[k-1][k-1])
+			extrapolated += rows[k][k]
+			total += 1 << k
+		case m.adaptive != nil:
+			res, e, points, err := m.adaptive(p.a, p.b, share, counted)
+			if err != nil {
+				return fmt.Errorf("%s: %w", m.name, err)
+			}
+			ans += res
+			errorEstimate += e
+			total += len(points) - 1
+			for i, t := range points {
+				partition = append(partition, p.x(t))
+				if extrapolate && i > 0 {
+					extrapolated += richardson(points[i-1], t, 2, m, p.g)
+				}
+			}
+		default:
+			res, e, n, err := integrate(p.a, p.b, share, m, counted)
+			if err != nil {
+				return err
+			}
+			ans += res
+			errorEstimate += e
+			total += n
+			if extrapolate {
+				// the same two sums as the last doubling of Runge's rule
+				if m.estimate == nil {
+					n /= 2
+				}
+				extrapolated += richardson(p.a, p.b, n, m, p.g)
+			}
+		}
+	}
+
+	fmt.Println("I =", ans)
+	if extrapolate {
+		fmt.Println("I (Richardson) =", extrapolated)
+	}
+	fmt.Println("Eps =", errorEstimate)
+	fmt.Println("N =", total)
+	fmt.Println("Evaluations by the method =", evaluations)
+	fmt.Println("Evaluations checking the integrand =", checks)
+	if partition != nil {
+		printPartition(partition)
+	}
+	return nil
+}
+
+// printPartition prints the bounds of the parts in x. The pieces of an
+// improper integral share their ends, those are printed once.
+func printPartition(points []float64) {
+	sort.Float64s(points)
+	var xs []string
+	for i, x := range points {
+		if i == 0 || x != points[i-1] {
+			xs = append(xs, fmt.Sprintf("%g", x))
+		}
+	}
+	fmt.Printf("Partition (%d parts): %s\n", len(xs)-1, strings.Join(xs, " "))
+}
+
+// integrate doubles n until the estimate of the error is below eps, the
+// method's own one if it has it and Runge's otherwise
+func integrate(a, b, eps float64, m Method, f func(x float64) float64) (float64, float64, int, error) {
+	if m.estimate != nil {
+		return integrateEstimated(a, b, eps, m, f)
+	}
+
 	n := 4
 	res0 := m.f(a, b, n, f)
 	res1 := m.f(a, b, n*2, f)
 
 	for math.Abs(res1-res0)/m.num > eps {
 		n *= 2
+		if n > maxN {
+			return 0, 0, 0, fmt.Errorf("no convergence on [%g, %g] with n = %d", a, b, n)
+		}
 		res1, res0 = m.f(a, b, n*2, f), res1
 	}
 
-	fmt.Println("I =", res1)
-	fmt.Println("Eps =", math.Abs(res1-res0)/m.num)
-	fmt.Println("N =", n*2)
+	if math.IsNaN(res1) || math.IsInf(res1, 0) {
+		return 0, 0, 0, fmt.Errorf("the sum on [%g, %g] is %v", a, b, res1)
+	}
+	return res1, math.Abs(res1-res0) / m.num, n * 2, nil
+}
+
+func integrateEstimated(a, b, eps float64, m Method, f func(x float64) float64) (float64, float64, int, error) {
+	n := 1
+	res, e := m.estimate(a, b, n, f)
+
+	for !(e <= eps) {
+		n *= 2
+		if n > maxN {
+			return 0, 0, 0, fmt.Errorf("no convergence on [%g, %g] with n = %d", a, b, n)
+		}
+		res, e = m.estimate(a, b, n, f)
+	}
+
+	if math.IsNaN(res) || math.IsInf(res, 0) {
+		return 0, 0, 0, fmt.Errorf("the sum on [%g, %g] is %v", a, b, res)
+	}
+	return res, e, n, nil
 }
 
 func LeftRectangleMethod(a, b float64, n int, f func(x float64) float64) float64 {
 	h := (b - a) / float64(n)
 	ans := float64(0)
 
-	for i := a; i <= b; i += h {
-		ans += f(i) * h
+	for i := 0; i < n; i++ {
+		ans += f(a+float64(i)*h) * h
 	}
 
 	return ans
@@ -35,8 +173,8 @@ func MiddleRectangleMethod(a, b float64, n int, f func(x float64) float64) float
 	h := (b - a) / float64(n)
 	ans := float64(0)
 
-	for i := a; i <= b; i += h {
-		ans += f(i+h/2) * h
+	for i := 0; i < n; i++ {
+		ans += f(a+(float64(i)+0.5)*h) * h
 	}
 
 	return ans
@@ -46,8 +184,8 @@ func RightRectangleMethod(a, b float64, n int, f func(x float64) float64) float6
 	h := (b - a) / float64(n)
 	ans := float64(0)
 
-	for i := a; i <= b; i += h {
-		ans += f(i+h) * h
+	for i := 1; i <= n; i++ {
+		ans += f(a+float64(i)*h) * h
 	}
 
 	return ans
@@ -57,8 +195,8 @@ func TrapezoidalMethod(a, b float64, n int, f func(x float64) float64) float64 {
 	h := (b - a) / float64(n)
 	ans := float64(0)
 
-	for i := a + h; i < b; i += h {
-		ans += f(i)
+	for i := 1; i < n; i++ {
+		ans += f(a + float64(i)*h)
 	}
 
 	return h * ((f(a)+f(b))/2 + ans)
@@ -68,11 +206,11 @@ func SimpsonMethod(a, b float64, n int, f func(x float64) float64) float64 {
 	h := (b - a) / float64(n)
 	ans := f(a) + f(b)
 
-	for i := a + h; i < b; i += 2 * h {
-		ans += 4 * f(i)
+	for i := 1; i < n; i += 2 {
+		ans += 4 * f(a+float64(i)*h)
 	}
-	for i := a + 2*h; i < b; i += 2 * h {
-		ans += 2 * f(i)
+	for i := 2; i < n; i += 2 {
+		ans += 2 * f(a+float64(i)*h)
 	}
 
 	return h / 3 * ans